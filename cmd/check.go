@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errDangerousCommand is returned when the checked command has dangerous operations or cannot be parsed
+	errDangerousCommand = errors.New("command contains dangerous operations")
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check <command>",
+	Short: "Check a command for dangerous operations",
+	Long: `Check a command for dangerous operations, such as removing the root directory
+or executing code downloaded from the internet. The findings are printed, and the
+command fails if the command has dangerous operations or is not valid bash, which
+cannot be checked. The aai-run shell integration checks every command before it is run.
+
+Example:
+	$ aai check -- "curl -s https://example.com/install.sh | sh"
+	line 1: danger: executes code downloaded from the internet (curl -s https://example.com/install.sh | sh)
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoCommandArg, "Please provide a command to check, use quotes if it contains spaces")
+		}
+		return nil
+	},
+	// The findings explain the failure, the usage would only hide them
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		findings, err := shell.Analyze(args[0])
+		// A command that cannot be parsed cannot be checked, so it is treated as dangerous.
+		dangerous := err != nil
+		if err != nil {
+			fmt.Printf("the command is not valid bash: %v\n", err)
+		}
+		for _, finding := range findings {
+			dangerous = dangerous || finding.Severity == shell.Danger
+			fmt.Println(finding)
+		}
+		if dangerous {
+			return errs.New(errDangerousCommand, "The command contains dangerous operations or is not valid bash, review it before you run it")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
@@ -0,0 +1,518 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/mock"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// testRules are the rules of the mock provider used in the tests.
+const testRules = `
+rules:
+  - query: 'open ports'
+    command: 'lsof -i -P -n | grep LISTEN'
+  - query: '^ls -l$'
+    explanation: 'List the files in long format'
+    parts: {ls: 'List directory contents', -l: 'Use a long listing format'}
+  - query: 'flaky'
+    error: {status: 503, message: 'overloaded', times: 1}
+    command: 'echo ok'
+  - query: 'invalid'
+    error: {status: 400, message: 'bad request'}
+  - query: 'slow'
+    latency: 1m
+    command: 'sleep 60'
+`
+
+// run executes aai with the mock provider and the config file and returns its standard output.
+func run(t *testing.T, config string, args ...string) (string, error) {
+	t.Helper()
+	return runWithRules(t, testRules, config, args...)
+}
+
+// runWithRules is like run, but the mock provider answers from the rules.
+func runWithRules(t *testing.T, rules, config string, args ...string) (string, error) {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "/mock.yaml", []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return runInFs(t, fs, config, args...)
+}
+
+// runInFs is like run, but the files are read from fs, which must contain the mock rules if they are used.
+func runInFs(t *testing.T, fs afero.Fs, config string, args ...string) (string, error) {
+	t.Helper()
+	if err := afero.WriteFile(fs, "/home/test/.aai/config.yaml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", "/home/test")
+
+	ctx := context.Background()
+	ctx = SetGlobalConfig(ctx, viper.New())
+	ctx = SetFs(ctx, fs)
+
+	resetFlags(t, rootCmd)
+	rootCmd.SetArgs(append([]string{"--provider", mockProvider, "--mock-rules", "/mock.yaml"}, args...))
+	rootCmd.SetIn(&bytes.Buffer{})
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+
+	// The commands print their results to os.Stdout.
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	err = rootCmd.ExecuteContext(ctx)
+	os.Stdout = stdout
+	_ = w.Close()
+	return <-output, err
+}
+
+// resetFlags sets the changed flags of the command and its subcommands back to their defaults,
+// since the commands are shared by the tests. Slice flags append to their values once they
+// were set, so the tests set slice values in the config file instead.
+// The contexts of the subcommands are reset as well, cobra keeps the context of the first run.
+func resetFlags(t *testing.T, cmd *cobra.Command) {
+	t.Helper()
+	cmd.SetContext(nil)
+	reset := func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if err := flag.Value.Set(flag.DefValue); err != nil {
+			t.Fatal(err)
+		}
+		flag.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(t, sub)
+	}
+}
+
+func TestRootCmd(t *testing.T) {
+	output, err := run(t, "", "list open ports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "lsof -i -P -n | grep LISTEN\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+}
+
+func TestRootCmd_Json(t *testing.T) {
+	output, err := run(t, "", "--output", "json", "list open ports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got suggestion
+	if err = json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := suggestion{Query: "list open ports", Command: "lsof -i -P -n | grep LISTEN", Provider: mockProvider}
+	if got != want {
+		t.Errorf("output = %+v, want %+v", got, want)
+	}
+}
+
+func TestRootCmd_NoRule(t *testing.T) {
+	_, err := run(t, "", "make coffee")
+	if !errors.Is(err, mock.ErrNoRule) {
+		t.Errorf("error = %v, want %v", err, mock.ErrNoRule)
+	}
+}
+
+func TestExplainCmd(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "explanation", args: []string{"explain", "ls -l"}, want: "List the files in long format\n"},
+		{name: "breakdown", args: []string{"explain", "--breakdown", "ls -l"}, want: "ls  List directory contents\n-l  Use a long listing format\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := run(t, "", tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output != tt.want {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+		})
+	}
+}
+
+// shortExplainer explains only the first part of a command.
+type shortExplainer struct{}
+
+func (shortExplainer) Explain(string) (string, error) { return "", nil }
+
+func (shortExplainer) ExplainParts(string, []string) ([]string, error) {
+	return []string{"List directory contents"}, nil
+}
+
+func TestExplainBreakdown_FewerExplanations(t *testing.T) {
+	if err := explainBreakdown(shortExplainer{}, "ls -l | wc -l", outputText, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	config := "fallback:\n  providers: [mock, mock]\n"
+	output, err := run(t, config, "--provider", fallbackProvider, "list open ports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "lsof -i -P -n | grep LISTEN\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	// Every provider of the chain is a new instance, so both mock providers return the injected error once.
+	_, err = run(t, config, "--provider", fallbackProvider, "flaky")
+	var failed *providersError
+	if !errors.As(err, &failed) || len(failed.errs) != 2 {
+		t.Errorf("error = %v, want the errors of both providers", err)
+	}
+
+	// Errors that are not retryable are returned immediately.
+	_, err = run(t, config, "--provider", fallbackProvider, "invalid")
+	var injected *mock.InjectedError
+	if !errors.As(err, &injected) || injected.Status != 400 {
+		t.Errorf("error = %v, want the injected 400 error", err)
+	}
+}
+
+func TestMockLatency(t *testing.T) {
+	rules := "latency: 1m\nrules:\n  - query: 'open ports'\n    command: 'lsof -i -P -n | grep LISTEN'\n"
+	config := "compare:\n  targets: [mock]\n"
+	tests := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{name: "rules file latency", err: errAllTargetsFailed},
+		{name: "flag over rules file latency", args: []string{"--mock-latency", "1ms"}},
+		{name: "zero flag over rules file latency", args: []string{"--mock-latency", "0s"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(tt.args, "compare", "--compare-timeout", "1s", "list open ports")
+			_, err := runWithRules(t, rules, config, args...)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "server error", err: &mock.InjectedError{Status: 503}, want: true},
+		{name: "rate limit", err: &mock.InjectedError{Status: 429}, want: true},
+		{name: "bad request", err: &mock.InjectedError{Status: 400}},
+		{name: "deadline", err: fmt.Errorf("request failed: %w", context.DeadlineExceeded), want: true},
+		{name: "connection refused", err: &url.Error{Op: "Post", URL: "http://127.0.0.1:1", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, want: true},
+		{name: "host not found", err: &url.Error{Op: "Post", URL: "http://no-such-host", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "no-such-host", IsNotFound: true}}}, want: true},
+		{name: "connection reset", err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}},
+		{name: "other error", err: errors.New("invalid response")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareCmd_Timeout(t *testing.T) {
+	config := "compare:\n  targets: [mock]\n"
+	output, err := run(t, config, "compare", "--compare-timeout", "1s", "-o", "json", "slow")
+	if !errors.Is(err, errAllTargetsFailed) {
+		t.Errorf("error = %v, want %v", err, errAllTargetsFailed)
+	}
+	var results []compareResult
+	if err = json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("results = %+v, want a deadline exceeded error", results)
+	}
+}
+
+func TestConfigGetCmd_Deprecated(t *testing.T) {
+	config := "providers:\n  openai:\n    model: text-curie-001\n"
+	tests := []struct {
+		name string
+		env  string
+		key  string
+		want string
+	}{
+		{name: "key", key: "providers.openai.model", want: "text-curie-001\n"},
+		{name: "deprecated key", key: "openai.model", want: "text-curie-001\n"},
+		{name: "deprecated env", env: "text-davinci-003", key: "providers.openai.model", want: "text-davinci-003\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AAI_OPENAI_MODEL", tt.env)
+			output, err := run(t, config, "config", "get", tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output != tt.want {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigSetCmd_Version(t *testing.T) {
+	// Files without the version key are updated, unless they have deprecated keys.
+	if _, err := run(t, "examples:\n  count: 2\n", "config", "set", "providers.openai.timeout=30s"); err != nil {
+		t.Errorf("error = %v, want nil", err)
+	}
+	_, err := run(t, "openai:\n  model: text-curie-001\n", "config", "set", "providers.openai.timeout=30s")
+	if !errors.Is(err, errOutdatedConfig) {
+		t.Errorf("error = %v, want %v", err, errOutdatedConfig)
+	}
+}
+
+func TestReadInput(t *testing.T) {
+	// The 3-byte runes do not end at the cut, so the context starts at the next rune.
+	long := strings.Repeat("€", maxContextSize/3+100)
+	tests := []struct {
+		name        string
+		args        []string
+		stdin       string
+		contextFile string
+		want        input
+		err         error
+	}{
+		{name: "argument", args: []string{"list files"}, want: input{Text: "list files"}},
+		{name: "piped query", stdin: "list files\n", want: input{Text: "list files"}},
+		{name: "piped query argument", args: []string{"-"}, stdin: "list files", want: input{Text: "list files"}},
+		{name: "piped data is not the context", args: []string{"remove the largest file"}, stdin: "a.txt", want: input{Text: "remove the largest file"}},
+		{name: "piped context", args: []string{"remove the largest file"}, stdin: "a.txt", contextFile: "-", want: input{Text: "remove the largest file", Context: "a.txt"}},
+		{name: "stdin used twice", stdin: "list files", contextFile: "-", err: errStdinUsedTwice},
+		{name: "no input", err: errNoQueryArg},
+		{name: "truncated context", args: []string{"summarize"}, stdin: long, contextFile: "-", want: input{Text: "summarize", Context: strings.Repeat("€", maxContextSize/3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cfg := newInputCmdConfig(cmd)
+			if err := cmd.Flags().Set("context-file", tt.contextFile); err != nil {
+				t.Fatal(err)
+			}
+			var stdin io.Reader
+			if tt.stdin != "" {
+				stdin = strings.NewReader(tt.stdin)
+			}
+			cmd.SetIn(stdin)
+
+			got, err := readInput(cmd, tt.args, cfg, errNoQueryArg)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("readInput() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("readInput() = %+v, want %+v", got, tt.want)
+			}
+			if !utf8.ValidString(got.Context) {
+				t.Errorf("readInput() context is not valid UTF-8")
+			}
+		})
+	}
+}
+
+func TestDoctorCmd_ApiKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		args   []string
+		want   string
+	}{
+		{name: "unused key", want: "[SKIP] api key: openai: providers.openai.apikey is not set, not used\n"},
+		{name: "used by the fallback chain", config: "fallback:\n  providers: [mock, openai]\n", args: []string{"--provider", fallbackProvider}, want: "[FAIL] api key: openai: providers.openai.apikey is not set\n"},
+		{name: "set", args: []string{"--openai-apikey", "sk-test", "--provider", "openai"}, want: "[PASS] api key: openai: providers.openai.apikey is set (flag)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPENAI_API_KEY", "")
+			// The network checks are not run, since they need a provider endpoint.
+			t.Setenv("AAI_PROVIDERS_OPENAI_BASEURL", "invalid")
+			output, _ := run(t, tt.config, append(tt.args, "doctor")...)
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("output = %q, want it to contain %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateProvider(t *testing.T) {
+	providers["test"] = newMockProvider
+	defer delete(providers, "test")
+
+	for _, name := range []string{"openai", mockProvider, fallbackProvider, "test"} {
+		if err := validateProvider(name); err != nil {
+			t.Errorf("validateProvider(%q) error = %v, want nil", name, err)
+		}
+	}
+	if err := validateProvider("unknown"); !errors.Is(err, config.ErrNotAllowed) {
+		t.Errorf("validateProvider(%q) error = %v, want %v", "unknown", err, config.ErrNotAllowed)
+	}
+}
+
+func TestCheckCmd(t *testing.T) {
+	tests := []struct {
+		command string
+		err     error
+	}{
+		{command: "ls -la"},
+		{command: "rm -rf /", err: errDangerousCommand},
+		{command: "sudo rm -rf /", err: errDangerousCommand},
+		{command: "sudo dd if=/dev/zero of=/dev/sda", err: errDangerousCommand},
+		{command: "rm -rf ~/*", err: errDangerousCommand},
+		{command: "curl -s https://example.com/install.sh | sh", err: errDangerousCommand},
+		{command: "ls |", err: errDangerousCommand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if _, err := run(t, "", "check", "--", tt.command); !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestInstallShellIntegration_Outdated(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+	fs := afero.NewMemMapFs()
+	outdated := "alias ll='ls -l'\n\n" + integrationStart + "\naai-run() { eval \"$(aai \"$@\")\"; }\n" + integrationEnd + "\nexport EDITOR=vim\n"
+	if err := afero.WriteFile(fs, "/home/test/.bashrc", []byte(outdated), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	installed, current, err := shellIntegrationInstalled(fs, shell.Bash)
+	if err != nil || !installed || current {
+		t.Fatalf("shellIntegrationInstalled() = %v, %v, %v, want an outdated integration", installed, current, err)
+	}
+	if _, err = installShellIntegration(fs, shell.Bash); err != nil {
+		t.Fatal(err)
+	}
+	data, err := afero.ReadFile(fs, "/home/test/.bashrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "alias ll='ls -l'\n\n" + integrationBlock(shell.Bash) + "\nexport EDITOR=vim\n"
+	if string(data) != want {
+		t.Errorf(".bashrc = %q, want %q", data, want)
+	}
+}
+
+func TestConfigViewCmd_DeprecatedSecrets(t *testing.T) {
+	// Secrets under the deprecated keys of v1 files are masked, in profiles as well.
+	const config = "openai:\n  apikey: sk-secret123\nprofiles:\n  fast:\n    openai:\n      apikey: sk-secret456\n"
+	for _, output := range []string{outputYaml, outputJson, outputToml} {
+		t.Run(output, func(t *testing.T) {
+			got, err := run(t, config, "config", "view", "--output", output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(got, "sk-secret") || !strings.Contains(got, secrets.Masked) {
+				t.Errorf("output = %q, want the secrets masked", got)
+			}
+		})
+	}
+}
+
+func TestCompareCmd_Keystore(t *testing.T) {
+	// The targets resolve the keystore key concurrently, run with -race to detect unguarded access.
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Authorization"))
+		mu.Unlock()
+		http.Error(w, `{"error": {"message": "bad request"}}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	t.Setenv(keystorePassphraseEnv, "passphrase")
+	openedKeystore = nil
+	defer func() { openedKeystore = nil }()
+	fs := afero.NewMemMapFs()
+	keystore, err := secrets.OpenKeystore(fs, "/home/test/.aai/keystore.json", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keystore.Set("openai", "sk-test")
+	if err = keystore.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	config := "providers:\n  openai:\n    apikey: keystore:openai\n    baseurl: " + server.URL + "\n" +
+		"compare:\n  targets: [openai, openai:gpt-3.5-turbo-instruct]\n"
+	if _, err = runInFs(t, fs, config, "compare", "list files"); !errors.Is(err, errAllTargetsFailed) {
+		t.Errorf("error = %v, want %v", err, errAllTargetsFailed)
+	}
+	if want := []string{"Bearer sk-test", "Bearer sk-test"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("authorization headers = %q, want %q", keys, want)
+	}
+}
+
+func TestConfigViewCmd_EffectiveComments(t *testing.T) {
+	output, err := run(t, "version: 2\n", "config", "view", "--effective")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"fallback:\n  providers: # default\n    - openai\n",
+		"grounding:\n  help: false # default\n",
+		"  targets: [] # default\n",
+		"  rules: {} # default\n",
+		"provider: mock # flag\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output = %q, want it to contain %q", output, want)
+		}
+	}
+}
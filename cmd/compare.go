@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/openai"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errAllTargetsFailed is returned when none of the compared targets answers
+	errAllTargetsFailed = errors.New("all targets failed")
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <query>",
+	Short: "Compare the suggestions of several providers and models",
+	Long: `Compare the suggestions of several providers and models.
+The query is sent concurrently to every target in compare.targets (--targets),
+or to every provider if no targets are configured. A target is a provider name with
+an optional model, e.g. "openai" or "openai:gpt-3.5-turbo-instruct".
+At most compare.concurrency requests run at the same time and all of them share
+the compare.timeout deadline.
+
+The suggestions are shown side by side with the latency, the number of tokens
+and the estimated cost of every target.
+
+Example:
+    $ aai compare --targets openai:text-davinci-003,openai:gpt-3.5-turbo-instruct "list open ports"
+`,
+	Args: maxOneArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output := compareCmdConfig.Output.Get()
+		if output != outputText && output != outputJson {
+			return errs.New(errUnknownOutput, fmt.Sprintf("Unknown output format %q, use %q or %q", output, outputText, outputJson))
+		}
+
+		in, err := readInput(cmd, args, compareCmdConfig.InputCmdConfig, errs.New(errNoQueryArg, "Please provide a query argument"))
+		if err != nil {
+			return err
+		}
+		opts, err := suggestOptions(cmd, in)
+		if err != nil {
+			return err
+		}
+		targets, err := configuredTargets()
+		if err != nil {
+			return err
+		}
+
+		results, first := runTargets(cmd.Context(), targets, in.Text, opts, false)
+		if output == outputJson {
+			err = printJson(results)
+		} else {
+			err = printResults(results)
+		}
+		if err != nil {
+			return err
+		}
+		if first < 0 {
+			return errs.New(errAllTargetsFailed, "None of the targets answered")
+		}
+		return nil
+	},
+}
+
+// compareTarget is a provider with an optional model override.
+type compareTarget struct {
+	provider string
+	model    string
+}
+
+// parseTarget parses the target, e.g. "openai" or "openai:gpt-3.5-turbo-instruct".
+func parseTarget(spec string) (compareTarget, error) {
+	provider, model, _ := strings.Cut(spec, ":")
+	if _, ok := providers[provider]; !ok || provider == fallbackProvider {
+		return compareTarget{}, fmt.Errorf("unknown provider %q", provider)
+	}
+	return compareTarget{provider: provider, model: model}, nil
+}
+
+func (t compareTarget) String() string {
+	if t.model == "" {
+		return t.provider
+	}
+	return t.provider + ":" + t.model
+}
+
+// validateTargets checks that the targets use registered providers.
+func validateTargets(specs []string) error {
+	for _, spec := range specs {
+		if _, err := parseTarget(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configuredTargets returns the targets of compare.targets,
+// or every registered provider except the fallback and mock providers.
+func configuredTargets() ([]compareTarget, error) {
+	specs := globalConfig.CompareTargets.Get()
+	if len(specs) == 0 {
+		for _, name := range providerNames() {
+			if name != fallbackProvider && name != mockProvider {
+				specs = append(specs, name)
+			}
+		}
+	}
+	targets := make([]compareTarget, 0, len(specs))
+	for _, spec := range specs {
+		target, err := parseTarget(spec)
+		if err != nil {
+			return nil, errs.New(err, fmt.Sprintf("Invalid %s: %v", globalConfig.CompareTargets.Key(), err))
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// compareResult is the answer of a target.
+type compareResult struct {
+	Target    string `json:"target"`
+	Command   string `json:"command,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+	Tokens    int    `json:"tokens,omitempty"`
+	// Cost is the estimated cost in USD, if the price of the model is known.
+	Cost *float64 `json:"cost_usd,omitempty"`
+
+	target compareTarget
+	err    error
+}
+
+// usageReporter is implemented by providers that report the token usage of their requests.
+type usageReporter interface {
+	Usage() openai.Usage
+}
+
+// suggestTarget asks the target for a suggestion and measures the latency and the cost.
+func suggestTarget(ctx context.Context, target compareTarget, query string, opts providerOptions) compareResult {
+	result := compareResult{Target: target.String(), target: target}
+	opts.model = target.model
+
+	start := time.Now()
+	provider, err := newProvider(ctx, target.provider, opts)
+	if err == nil {
+		result.Command, err = provider.Suggest(query)
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.err = err
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if reporter, ok := provider.(usageReporter); ok && reporter.Usage().TotalTokens > 0 {
+		model := target.model
+		if model == "" {
+			model = globalConfig.Model.Get()
+		}
+		usage := reporter.Usage()
+		result.Tokens = usage.TotalTokens
+		if cost, ok := usage.Cost(model); ok {
+			result.Cost = &cost
+		}
+	}
+	return result
+}
+
+// runTargets sends the query to the targets concurrently, at most compare.concurrency at a time,
+// within the compare.timeout deadline. It returns the results in the order of the targets and
+// the index of the first successful result, or -1. In race mode, the other requests are canceled
+// after the first successful result.
+func runTargets(ctx context.Context, targets []compareTarget, query string, opts providerOptions, race bool) ([]compareResult, int) {
+	ctx, cancel := context.WithTimeout(ctx, globalConfig.CompareTimeout.Get())
+	defer cancel()
+
+	results := make([]compareResult, len(targets))
+	first := -1
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, globalConfig.CompareConcurrency.Get())
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target compareTarget) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = compareResult{Target: target.String(), Error: ctx.Err().Error(), target: target, err: ctx.Err()}
+				return
+			}
+
+			result := suggestTarget(ctx, target, query, opts)
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = result
+			if result.err == nil && first < 0 {
+				first = i
+				if race {
+					cancel()
+				}
+			}
+		}(i, target)
+	}
+	wg.Wait()
+	return results, first
+}
+
+// raceTargets sends the query to the configured targets and returns the first successful result.
+func raceTargets(ctx context.Context, query string, opts providerOptions) (compareResult, error) {
+	targets, err := configuredTargets()
+	if err != nil {
+		return compareResult{}, err
+	}
+	results, first := runTargets(ctx, targets, query, opts, true)
+	if first < 0 {
+		failed := &providersError{}
+		for _, result := range results {
+			failed.add(result.Target, result.err)
+		}
+		return compareResult{}, failed
+	}
+	return results[first], nil
+}
+
+// printResults prints the results side by side as a table.
+func printResults(results []compareResult) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TARGET\tLATENCY\tTOKENS\tCOST\tSUGGESTION")
+	for _, result := range results {
+		tokens, cost := "-", "-"
+		if result.Tokens > 0 {
+			tokens = fmt.Sprint(result.Tokens)
+		}
+		if result.Cost != nil {
+			cost = fmt.Sprintf("$%.5f", *result.Cost)
+		}
+		suggestion := strings.ReplaceAll(result.Command, "\n", " ⏎ ")
+		if result.err != nil {
+			suggestion = "error: " + result.Error
+		}
+		latency := time.Duration(result.LatencyMs) * time.Millisecond
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.Target, latency, tokens, cost, suggestion)
+	}
+	return w.Flush()
+}
+
+type CompareCmdConfig struct {
+	InputCmdConfig
+
+	Output flags.Flag[string]
+}
+
+var compareCmdConfig CompareCmdConfig
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmdConfig = CompareCmdConfig{
+		InputCmdConfig: newInputCmdConfig(compareCmd),
+
+		Output: flags.StringP(compareCmd.Flags(), "output", "o", outputText, "output format (text|json)"),
+	}
+}
@@ -10,6 +10,16 @@ var configCmd = &cobra.Command{
 	Short: "Display or change current configuration",
 }
 
+// isConfigCommand returns true if the command is the config command or one of its subcommands.
+func isConfigCommand(cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd == configCmd {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 }
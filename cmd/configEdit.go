@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultEditor is used when neither $VISUAL nor $EDITOR is set.
+	defaultEditor = "vi"
+)
+
+var (
+	// errEditAborted is returned when the edited config is invalid and the user does not edit it again
+	errEditAborted = errors.New("config edit aborted")
+)
+
+// configEditCmd represents the config edit command
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the config file in $EDITOR",
+	Long: `Edit the config file in $VISUAL or $EDITOR.
+The changes are validated before they are saved. If they are invalid,
+the problems are displayed and the file can be edited again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fs := GetFs(cmd.Context())
+
+		file, err := usedConfigFile(GetGlobalConfig(cmd.Context()))
+		if err != nil {
+			return err
+		}
+		data, err := afero.ReadFile(fs, file)
+		if err != nil {
+			return fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+
+		// The config is edited in a copy, so that an invalid config is never saved.
+		tmp, err := afero.TempFile(fs, "", "aai-config-*"+filepath.Ext(file))
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		defer func() {
+			_ = fs.Remove(tmp.Name())
+		}()
+		if _, err = tmp.Write(data); err != nil {
+			return fmt.Errorf("failed to write temporary file: %w", err)
+		}
+		if err = tmp.Close(); err != nil {
+			return fmt.Errorf("failed to write temporary file: %w", err)
+		}
+
+		p := newPrompter(cmd)
+		for {
+			if err = runEditor(tmp.Name()); err != nil {
+				return err
+			}
+			problems, err := validateConfigFile(fs, tmp.Name())
+			if err != nil {
+				return err
+			}
+			if len(problems) == 0 {
+				break
+			}
+			for _, problem := range problems {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s:%d: %v\n", file, problem.line, problem.err)
+			}
+			again, err := p.confirm("The config is invalid. Edit again?", true)
+			if err != nil {
+				return err
+			}
+			if !again {
+				return errs.New(errEditAborted, "The config file was not changed")
+			}
+		}
+
+		edited, err := afero.ReadFile(fs, tmp.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read temporary file: %w", err)
+		}
+		if err = afero.WriteFile(fs, file, edited, 0644); err != nil {
+			return fmt.Errorf("failed to write config file %s: %w", file, err)
+		}
+		return nil
+	},
+}
+
+// runEditor opens the file in the user's editor and waits until it exits.
+// The editor can contain arguments, e.g. "code --wait".
+func runEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = defaultEditor
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", file)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %q: %w", editor, err)
+	}
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+}
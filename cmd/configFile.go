@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultConfigType is the type of new config files.
+	defaultConfigType = "yaml"
+)
+
+var (
+	// errNoConfigFile is returned when there is no config file to write to
+	errNoConfigFile = errors.New("no config file to write to")
+
+	// configFileTypes are the supported config file types, detected by the file extension.
+	// The order decides which file is used when a directory has none of them.
+	configFileTypes = []string{"yaml", "yml", "toml", "json"}
+)
+
+// configType returns the type of the config file by its extension, or the default type.
+func configType(file string) string {
+	ext := strings.TrimPrefix(filepath.Ext(file), ".")
+	for _, t := range configFileTypes {
+		if ext == t {
+			return t
+		}
+	}
+	return defaultConfigType
+}
+
+// encodeConfig returns the settings encoded in the config type.
+func encodeConfig(settings map[string]any, configType string) ([]byte, error) {
+	switch configType {
+	case "toml":
+		return toml.Marshal(settings)
+	case "json":
+		data, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+	return yaml.Marshal(settings)
+}
+
+// readConfigFile reads the config file, in the type detected by its extension.
+func readConfigFile(fs afero.Fs, file string) (*viper.Viper, error) {
+	fileConfig := viper.New()
+	fileConfig.SetFs(fs)
+	fileConfig.SetConfigFile(file)
+	fileConfig.SetConfigType(configType(file))
+	if err := fileConfig.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+	return fileConfig, nil
+}
+
+// reloadConfig replaces the contents of the config file with the settings, without writing the file.
+func reloadConfig(fileConfig *viper.Viper, settings map[string]any) error {
+	data, err := encodeConfig(settings, configType(fileConfig.ConfigFileUsed()))
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err = fileConfig.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to reload config: %w", err)
+	}
+	return nil
+}
+
+// flagChanges returns the global config values that were set with flags, by key.
+// The profile selection flag is not included.
+func flagChanges() (map[string]any, error) {
+	changes := make(map[string]any)
+	err := config.Traverse(globalConfig, func(value config.AnyValue) error {
+		if value.Key() == globalConfig.Profile.Key() {
+			return nil
+		}
+		if flag := value.Flag(); flag != nil && flag.Changed {
+			if err := value.Validate(); err != nil {
+				return errs.New(err, fmt.Sprintf("Invalid value: %v", err))
+			}
+			changes[value.Key()] = fileValue(value.GetAny())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to traverse config: %w", err)
+	}
+	return changes, nil
+}
+
+// fileValue returns the value as it is written to the config file.
+// Durations are written in their readable form, e.g. "1m30s", instead of nanoseconds.
+func fileValue(value any) any {
+	if duration, ok := value.(time.Duration); ok {
+		return duration.String()
+	}
+	return value
+}
+
+// usedConfigFile returns the config file that is written by config commands, or an errNoConfigFile error.
+func usedConfigFile(cfg *viper.Viper) (string, error) {
+	if cfg.ConfigFileUsed() == "" {
+		return "", errs.New(errNoConfigFile, "No config file found, run \"aai init\" to create one")
+	}
+	return cfg.ConfigFileUsed(), nil
+}
+
+// configValues returns the global config values by key.
+func configValues() (map[string]config.AnyValue, error) {
+	values := make(map[string]config.AnyValue)
+	err := config.Traverse(&globalConfig, func(value config.AnyValue) error {
+		values[value.Key()] = value
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to traverse config: %w", err)
+	}
+	return values, nil
+}
+
+// lookupValue returns the global config value with the key, or an errUnknownKey error.
+// Deprecated keys, such as "openai.model", return the value of their current key.
+func lookupValue(key string) (config.AnyValue, error) {
+	values, err := configValues()
+	if err != nil {
+		return nil, err
+	}
+	key = strings.ToLower(key)
+	if current, ok := currentKey(key); ok {
+		log.Warn().Msgf("Config key %s is deprecated, use %s", key, current)
+		key = current
+	}
+	value, ok := values[key]
+	if !ok {
+		return nil, errs.New(errUnknownKey, fmt.Sprintf("Unknown config key %q", key))
+	}
+	return value, nil
+}
+
+// unsetKey removes the key from the config file contents.
+// Viper cannot delete keys, so the contents are replaced without the key.
+// Parent sections left empty are removed as well.
+func unsetKey(fileConfig *viper.Viper, key string) error {
+	settings := fileConfig.AllSettings()
+	deleteKey(settings, strings.Split(strings.ToLower(key), "."))
+	return reloadConfig(fileConfig, settings)
+}
+
+// deleteKey deletes the nested key from the settings and returns true if the settings are empty.
+func deleteKey(settings map[string]any, path []string) bool {
+	if len(path) == 1 {
+		delete(settings, path[0])
+	} else if sub, ok := settings[path[0]].(map[string]any); ok && deleteKey(sub, path[1:]) {
+		delete(settings, path[0])
+	}
+	return len(settings) == 0
+}
+
+// updateConfigFile reads the config file, applies the update and writes the file back.
+// The file is created if it does not exist. Only the file contents are written,
+// without defaults, flags or profile overrides of the global config.
+// Files with deprecated keys of an older schema version must be migrated first.
+func updateConfigFile(fs afero.Fs, file string, update func(fileConfig *viper.Viper) error) error {
+	fileConfig := viper.New()
+	fileConfig.SetFs(fs)
+	fileConfig.SetConfigFile(file)
+	fileConfig.SetConfigType(configType(file))
+
+	exists, err := afero.Exists(fs, file)
+	if err != nil {
+		return fmt.Errorf("failed to check config file %s: %w", file, err)
+	}
+	if exists {
+		if err = fileConfig.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+		version, outdated, err := outdatedSettings(fileConfig.AllSettings())
+		if err != nil {
+			return errs.New(err, fmt.Sprintf("Cannot update %s: %v", file, err))
+		}
+		if outdated {
+			return errs.New(errOutdatedConfig, fmt.Sprintf("%s uses config version %d, run \"aai config migrate\" first", file, version))
+		}
+	}
+
+	if err = update(fileConfig); err != nil {
+		return err
+	}
+	fileConfig.Set(configVersionKey, configVersion)
+
+	if err = fs.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(file), err)
+	}
+	if err = fileConfig.WriteConfigAs(file); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", file, err)
+	}
+	return nil
+}
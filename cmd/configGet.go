@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errNoKeyArg is returned when no config key argument is provided
+	errNoKeyArg = errors.New("no config key argument provided")
+)
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Display the effective value of a config key",
+	Long: `Display the effective value of a config key.
+The source of the value (flag, env, profile, file or default) is printed to stderr.
+
+Example:
+	$ aai config get providers.openai.model
+	text-davinci-002
+	source: default
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoKeyArg, "Please provide a config key")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := lookupValue(args[0])
+		if err != nil {
+			return err
+		}
+
+		effective := value.GetAny()
+		if value.IsSecret() && !rootCmdConfig.ShowSecrets.Get() {
+			effective = secrets.Mask(fmt.Sprint(effective))
+		}
+		fmt.Println(fileValue(effective))
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "source: %s\n", value.Source(globalConfig.Profile.Get()))
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+}
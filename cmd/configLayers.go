@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+const (
+	// projectConfigFileName is the name of the project config file, without extension,
+	// discovered by walking up from the working directory to the repository root.
+	projectConfigFileName = ".aai"
+	// projectRootMarker marks the root of a repository, where the project config discovery stops.
+	projectRootMarker = ".git"
+)
+
+// projectConfigKeys are the keys, or key prefixes ending with a dot, that a project config file can set.
+// Other keys, such as API keys, secret references, base URLs and providers, are ignored,
+// so that a cloned repository cannot run commands or send the user's API key to another host.
+var projectConfigKeys = []string{configVersionKey, "examples.", "redact."}
+
+// configFile is a config file merged into the global config.
+type configFile struct {
+	// path is the path of the file.
+	path string
+	// project is true for the project config file.
+	project bool
+	// version is the schema version of the file, before it was migrated in memory.
+	version int
+	// outdated is true if the file has deprecated keys, which were migrated in memory.
+	outdated bool
+	// ignored are the keys of the project config file that are not allowed in project files.
+	ignored []string
+	// config holds the contents of the file.
+	config *viper.Viper
+}
+
+// configFiles are the config files merged into the global config, lowest precedence first.
+var configFiles []configFile
+
+// defaultConfigDirs returns the system and user config directories, lowest precedence first.
+func defaultConfigDirs() []string {
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" {
+		xdgConfigHome = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return []string{
+		"/etc/aai",
+		filepath.Join(xdgConfigHome, "aai"),
+		userConfigDir(),
+	}
+}
+
+// userConfigDir returns the aai directory in $HOME, where aai init writes the config file.
+func userConfigDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".aai")
+}
+
+// findProjectConfig returns the nearest project config file, walking up from dir
+// to the repository root. Outside of a repository, only dir is checked.
+func findProjectConfig(fs afero.Fs, dir string) (string, error) {
+	var dirs []string
+	for current := dir; ; current = filepath.Dir(current) {
+		dirs = append(dirs, current)
+		if isRoot, err := afero.Exists(fs, filepath.Join(current, projectRootMarker)); err != nil {
+			return "", err
+		} else if isRoot {
+			break
+		}
+		if filepath.Dir(current) == current {
+			// Not in a repository
+			dirs = dirs[:1]
+			break
+		}
+	}
+
+	for _, d := range dirs {
+		if path, exists, err := findConfigFile(fs, d, projectConfigFileName); err != nil {
+			return "", err
+		} else if exists {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// findConfigFile returns the config file in dir with the name and any of the supported extensions.
+// If there is no such file, the path with the default extension is returned.
+// Two files with different extensions are an error, since it is not clear which one to use.
+func findConfigFile(fs afero.Fs, dir, name string) (path string, exists bool, err error) {
+	for _, t := range configFileTypes {
+		candidate := filepath.Join(dir, name+"."+t)
+		found, err := afero.Exists(fs, candidate)
+		if err != nil {
+			return "", false, err
+		}
+		if !found {
+			continue
+		}
+		if exists {
+			return "", false, fmt.Errorf("found both %s and %s, remove one of them", path, candidate)
+		}
+		path, exists = candidate, true
+	}
+	if !exists {
+		path = filepath.Join(dir, name+"."+defaultConfigType)
+	}
+	return path, exists, nil
+}
+
+// configFilePaths returns the paths of the system and user config files, lowest precedence first,
+// followed by the project config file, if it is found. The files may not exist.
+func configFilePaths(fs afero.Fs) (paths []string, project string, err error) {
+	for _, dir := range defaultConfigDirs() {
+		path, _, err := findConfigFile(fs, dir, configFileName)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to find config file: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	if project, err = findProjectConfig(fs, wd); err != nil {
+		return nil, "", fmt.Errorf("failed to find project config: %w", err)
+	}
+	if project != "" {
+		paths = append(paths, project)
+	}
+	return paths, project, nil
+}
+
+// readConfigFiles merges the system, user and project config files into the config.
+// Files with an older schema version are migrated in memory. Only the projectConfigKeys
+// of the project config file are merged.
+// The config file used for writing is the user config file with the highest precedence,
+// the project config file is never written by config commands.
+func readConfigFiles(fs afero.Fs, cfg *viper.Viper) error {
+	paths, project, err := configFilePaths(fs)
+	if err != nil {
+		return err
+	}
+
+	configFiles = nil
+	for _, path := range paths {
+		exists, err := afero.Exists(fs, path)
+		if err != nil {
+			return fmt.Errorf("failed to check config file %s: %w", path, err)
+		}
+		if !exists {
+			continue
+		}
+
+		fileConfig, err := readConfigFile(fs, path)
+		if err != nil {
+			return err
+		}
+		settings := fileConfig.AllSettings()
+		version, outdated, err := outdatedSettings(settings)
+		if err != nil {
+			return fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		if outdated {
+			if _, err = migrateSettings(settings); err != nil {
+				return fmt.Errorf("failed to migrate config file %s: %w", path, err)
+			}
+			if err = reloadConfig(fileConfig, settings); err != nil {
+				return err
+			}
+		}
+		isProject := path == project
+		var ignored []string
+		if isProject {
+			if ignored, err = filterProjectConfig(fileConfig); err != nil {
+				return err
+			}
+			resolveProjectPaths(fileConfig, filepath.Dir(path))
+		}
+		if err = cfg.MergeConfigMap(fileConfig.AllSettings()); err != nil {
+			return fmt.Errorf("failed to merge config file %s: %w", path, err)
+		}
+		configFiles = append(configFiles, configFile{path: path, project: isProject, version: version, outdated: outdated, ignored: ignored, config: fileConfig})
+
+		if !isProject {
+			cfg.SetConfigFile(path)
+		}
+	}
+	return nil
+}
+
+// filterProjectConfig removes the keys that are not allowed in project config files
+// and returns the removed keys, sorted.
+func filterProjectConfig(fileConfig *viper.Viper) ([]string, error) {
+	settings := fileConfig.AllSettings()
+	var ignored []string
+	for _, key := range fileConfig.AllKeys() {
+		if !isProjectKey(key) {
+			ignored = append(ignored, key)
+			deleteKey(settings, strings.Split(key, "."))
+		}
+	}
+	if len(ignored) == 0 {
+		return nil, nil
+	}
+	sort.Strings(ignored)
+	return ignored, reloadConfig(fileConfig, settings)
+}
+
+// isProjectKey returns true if the key can be set in a project config file.
+func isProjectKey(key string) bool {
+	for _, allowed := range projectConfigKeys {
+		if key == allowed || (strings.HasSuffix(allowed, ".") && strings.HasPrefix(key, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveProjectPaths makes the relative file paths of the project config relative to the project directory,
+// so that a repository can ship its own examples.
+func resolveProjectPaths(fileConfig *viper.Viper, dir string) {
+	key := globalConfig.ExamplesFile.Key()
+	if path := fileConfig.GetString(key); path != "" && !filepath.IsAbs(os.ExpandEnv(path)) {
+		fileConfig.Set(key, filepath.Join(dir, path))
+	}
+}
+
+// configFileOf returns the path of the config file with the highest precedence that sets the key.
+func configFileOf(key string) string {
+	for i := len(configFiles) - 1; i >= 0; i-- {
+		if configFiles[i].config.InConfig(key) {
+			return configFiles[i].path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// backupTimeFormat is the time format in the names of config file backups.
+	backupTimeFormat = "20060102150405"
+)
+
+var (
+	// errTargetExists is returned when the converted config file would overwrite another file
+	errTargetExists = errors.New("target file exists")
+)
+
+// configMigrateCmd represents the config migrate command
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the config files to the current version or convert their format",
+	Long: `Upgrade the config files to the current version or convert their format.
+Older config files are still read, but files with deprecated keys are upgraded
+only in memory and cannot be changed with config commands until they are migrated.
+The migrations of every file are listed, for example the move of the openai.* keys
+into providers.openai.* in version 2. The openai.* keys of config commands and
+the AAI_OPENAI_* environment variables are still accepted as deprecated aliases.
+
+Use --to to convert the files to yaml, toml or json. The converted file replaces
+the original one, e.g. config.yaml becomes config.toml.
+Before a file is changed, it is copied to <file>.<time>.bak. Comments are not preserved.
+All merged config files are migrated, unless a file is selected with --file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fs := GetFs(cmd.Context())
+
+		to := configMigrateCmdConfig.To.Get()
+		if to != "" && to != outputYaml && to != outputToml && to != outputJson {
+			return errs.New(errUnknownOutput, fmt.Sprintf("Unknown format %q, use yaml, toml or json", to))
+		}
+
+		var files []string
+		if configMigrateCmdConfig.File.Changed() {
+			files = []string{configMigrateCmdConfig.File.Get()}
+		} else {
+			for _, file := range configFiles {
+				files = append(files, file.path)
+			}
+		}
+		if len(files) == 0 {
+			return errs.New(errNoConfigFile, "No config file found")
+		}
+
+		for _, file := range files {
+			if err := migrateConfigFile(cmd, fs, file, to); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// migrateConfigFile upgrades the config file to the current version and converts it to the format,
+// if it is not empty. The original file is backed up.
+func migrateConfigFile(cmd *cobra.Command, fs afero.Fs, file, format string) error {
+	fileConfig, err := readConfigFile(fs, file)
+	if err != nil {
+		return err
+	}
+	settings := fileConfig.AllSettings()
+	version, err := settingsVersion(settings)
+	if err != nil {
+		return errs.New(err, fmt.Sprintf("Cannot migrate %s: %v", file, err))
+	}
+	applied, err := migrateSettings(settings)
+	if err != nil {
+		return errs.New(err, fmt.Sprintf("Cannot migrate %s: %v", file, err))
+	}
+
+	target := file
+	if format != "" && !sameConfigType(configType(file), format) {
+		target = strings.TrimSuffix(file, filepath.Ext(file)) + "." + format
+	}
+	if len(applied) == 0 && target == file {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s is up to date\n", file)
+		return nil
+	}
+
+	data, err := encodeConfig(settings, configType(target))
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if configMigrateCmdConfig.DryRun.Get() {
+		printMigrations(cmd, file, target, version, applied)
+		fmt.Printf("# %s\n%s", target, data)
+		return nil
+	}
+
+	if target != file {
+		if exists, err := afero.Exists(fs, target); err != nil {
+			return fmt.Errorf("failed to check config file %s: %w", target, err)
+		} else if exists {
+			return errs.New(errTargetExists, fmt.Sprintf("Cannot convert %s, %s already exists", file, target))
+		}
+	}
+	backup, err := backupConfigFile(fs, file)
+	if err != nil {
+		return err
+	}
+	if err = afero.WriteFile(fs, target, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", target, err)
+	}
+	if target != file {
+		if err = fs.Remove(file); err != nil {
+			return fmt.Errorf("failed to remove config file %s: %w", file, err)
+		}
+	}
+
+	printMigrations(cmd, file, target, version, applied)
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Backup saved to %s\n", backup)
+	return nil
+}
+
+// sameConfigType returns true if the config types have the same format, e.g. "yml" and "yaml".
+func sameConfigType(a, b string) bool {
+	normalize := func(t string) string {
+		if t == "yml" {
+			return "yaml"
+		}
+		return t
+	}
+	return normalize(a) == normalize(b)
+}
+
+// backupConfigFile copies the config file next to it, with the current time in the name,
+// and returns the path of the copy. Existing backups are not overwritten.
+func backupConfigFile(fs afero.Fs, file string) (string, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+	name := fmt.Sprintf("%s.%s", file, time.Now().Format(backupTimeFormat))
+	backup := name + ".bak"
+	for i := 1; ; i++ {
+		exists, err := afero.Exists(fs, backup)
+		if err != nil {
+			return "", fmt.Errorf("failed to check backup %s: %w", backup, err)
+		}
+		if !exists {
+			break
+		}
+		backup = fmt.Sprintf("%s-%d.bak", name, i)
+	}
+	if err = afero.WriteFile(fs, backup, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup %s: %w", backup, err)
+	}
+	return backup, nil
+}
+
+// printMigrations prints the changes made to the config file.
+func printMigrations(cmd *cobra.Command, file, target string, version int, applied []configMigration) {
+	out := cmd.ErrOrStderr()
+	if target != file {
+		_, _ = fmt.Fprintf(out, "%s: converted to %s\n", file, target)
+	}
+	if len(applied) > 0 {
+		_, _ = fmt.Fprintf(out, "%s: upgraded from version %d to %d\n", file, version, configVersion)
+	}
+	for _, migration := range applied {
+		_, _ = fmt.Fprintf(out, "  version %d: %s\n", migration.version, migration.description)
+	}
+}
+
+type ConfigMigrateCmdConfig struct {
+	File   flags.Flag[string]
+	To     flags.Flag[string]
+	DryRun flags.Flag[bool]
+}
+
+var configMigrateCmdConfig ConfigMigrateCmdConfig
+
+func init() {
+	var err error
+	configCmd.AddCommand(configMigrateCmd)
+
+	configMigrateCmdConfig = ConfigMigrateCmdConfig{
+		File:   flags.StringP(configMigrateCmd.Flags(), "file", "f", "", "file to migrate, all merged config files by default"),
+		To:     flags.String(configMigrateCmd.Flags(), "to", "", "convert the files to the format: yaml, toml or json"),
+		DryRun: flags.Bool(configMigrateCmd.Flags(), "dry-run", false, "print the migrated files instead of writing them"),
+	}
+	if err = configMigrateCmd.MarkFlagFilename("file", configFileTypes...); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// configProfileCmd represents the config profile command
+var configProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage config profiles",
+	Long: `Manage config profiles.
+A profile is a named set of config values, such as a provider, a model
+and its parameters, that override the values from the config file.
+The profile is selected with --profile flag, AAI_PROFILE environment
+variable or "aai config profile use" command, in that order.
+
+Example config file:
+	openai:
+	  model: text-davinci-002
+	profiles:
+	  fast:
+	    openai:
+	      model: text-curie-001
+`,
+}
+
+func init() {
+	configCmd.AddCommand(configProfileCmd)
+}
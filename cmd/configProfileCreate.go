@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// errProfileExists is returned when a created profile already exists
+	errProfileExists = errors.New("profile already exists")
+	// errNoProfileValues is returned when a profile is created without values
+	errNoProfileValues = errors.New("no profile values provided")
+)
+
+// configProfileCreateCmd represents the config profile create command
+var configProfileCreateCmd = &cobra.Command{
+	Use:   "create <profile>",
+	Short: "Create a profile with values set using flags",
+	Long: `Create a profile with values set using flags.
+
+Example:
+	$ aai config profile create fast --openai-model text-curie-001 --openai-maxtokens 50
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoProfileArg, "Please provide a profile name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := GetGlobalConfig(cmd.Context())
+		profile := args[0]
+
+		for _, existing := range config.Profiles(cfg) {
+			if existing == profile {
+				return errs.New(errProfileExists, fmt.Sprintf("Profile %q already exists, use \"aai config set --profile %s\" to change it", profile, profile))
+			}
+		}
+
+		changes, err := flagChanges()
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return errs.New(errNoProfileValues, "Please set at least one profile value using flags")
+		}
+
+		file, err := usedConfigFile(cfg)
+		if err != nil {
+			return err
+		}
+		err = updateConfigFile(GetFs(cmd.Context()), file, func(fileConfig *viper.Viper) error {
+			for key, value := range changes {
+				fileConfig.Set(config.ProfileKey(profile, key), value)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configProfileCmd.AddCommand(configProfileCreateCmd)
+}
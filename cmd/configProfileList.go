@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// configProfileListCmd represents the config profile list command
+var configProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List config profiles, the active profile is marked with *",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := GetGlobalConfig(cmd.Context())
+		active := globalConfig.Profile.Get()
+
+		for _, profile := range config.Profiles(cfg) {
+			marker := " "
+			if profile == active {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, profile)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configProfileCmd.AddCommand(configProfileListCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// errNoProfileArg is returned when no profile argument is provided
+	errNoProfileArg = errors.New("no profile argument provided")
+)
+
+// configProfileUseCmd represents the config profile use command
+var configProfileUseCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "Select the profile used by default",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoProfileArg, "Please provide a profile name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := GetGlobalConfig(cmd.Context())
+		profile := args[0]
+
+		if cfg.Sub(fmt.Sprintf("%s.%s", config.ProfilesKey, profile)) == nil {
+			return errs.New(config.ErrUnknownProfile, fmt.Sprintf("Profile %q is not defined, available profiles: %v", profile, config.Profiles(cfg)))
+		}
+
+		file, err := usedConfigFile(cfg)
+		if err != nil {
+			return err
+		}
+		err = updateConfigFile(GetFs(cmd.Context()), file, func(fileConfig *viper.Viper) error {
+			fileConfig.Set(globalConfig.Profile.Key(), profile)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configProfileCmd.AddCommand(configProfileUseCmd)
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	// configVersionKey is the config file key of the schema version.
+	configVersionKey = "version"
+	// configVersion is the current schema version of the config files.
+	// Files without the version key have version 1.
+	configVersion = 2
+	// providersKey is the config key of the provider settings, e.g. "providers.openai.model".
+	providersKey = "providers"
+)
+
+var (
+	// errOutdatedConfig is returned when the config file has an older schema version
+	errOutdatedConfig = errors.New("outdated config version")
+	// errUnsupportedVersion is returned when the config file has an invalid or newer schema version
+	errUnsupportedVersion = errors.New("unsupported config version")
+)
+
+// configMigration upgrades config file settings to its schema version.
+type configMigration struct {
+	version     int
+	description string
+	// migrate upgrades the settings in place. It is applied to the top level settings
+	// and to the settings of every profile.
+	migrate func(settings map[string]any)
+}
+
+// configMigrations are the schema migrations, ordered by version.
+var configMigrations = []configMigration{
+	{version: 2, description: "move the openai.* keys into providers.openai.*", migrate: moveToProviders("openai")},
+}
+
+// deprecatedKeyPrefixes are the key prefixes of older schema versions, by their current prefix.
+// The deprecated keys and their AAI_ environment variables are still accepted.
+var deprecatedKeyPrefixes = map[string]string{
+	"providers.openai.": "openai.",
+}
+
+// deprecatedEnvs are the AAI_ environment variables of the deprecated keys, by the current variable.
+var deprecatedEnvs = make(map[string]string)
+
+// deprecatedEnv binds the AAI_ environment variable of the deprecated key of the value,
+// e.g. AAI_OPENAI_MODEL for "providers.openai.model".
+func deprecatedEnv[T any](key string) config.Option[T] {
+	for prefix, deprecated := range deprecatedKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			env := config.EnvName(deprecated + strings.TrimPrefix(key, prefix))
+			deprecatedEnvs[config.EnvName(key)] = env
+			return config.WithEnv[T](env)
+		}
+	}
+	return config.WithEnv[T]()
+}
+
+// currentKey returns the current key of a deprecated key, e.g. "providers.openai.model" for "openai.model".
+// It returns false if the key is not deprecated.
+func currentKey(key string) (string, bool) {
+	for prefix, deprecated := range deprecatedKeyPrefixes {
+		if strings.HasPrefix(key, deprecated) {
+			return prefix + strings.TrimPrefix(key, deprecated), true
+		}
+	}
+	return key, false
+}
+
+// settingsVersion returns the schema version of the config file settings.
+func settingsVersion(settings map[string]any) (int, error) {
+	raw, ok := settings[configVersionKey]
+	if !ok {
+		return 1, nil
+	}
+	version, err := cast.ToIntE(raw)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("%w %v", errUnsupportedVersion, raw)
+	}
+	if version > configVersion {
+		return 0, fmt.Errorf("%w %d, the newest supported version is %d, please upgrade aai", errUnsupportedVersion, version, configVersion)
+	}
+	return version, nil
+}
+
+// outdatedSettings returns the schema version of the config file settings, and true if a migration
+// changes them. Settings of an older version that no migration changes, such as files without
+// deprecated keys that were written before the version key was added, are not outdated.
+func outdatedSettings(settings map[string]any) (int, bool, error) {
+	version, err := settingsVersion(settings)
+	if err != nil || version == configVersion {
+		return version, false, err
+	}
+	migrated := copySettings(settings)
+	if _, err = migrateSettings(migrated); err != nil {
+		return 0, false, err
+	}
+	delete(migrated, configVersionKey)
+	original := copySettings(settings)
+	delete(original, configVersionKey)
+	return version, !reflect.DeepEqual(migrated, original), nil
+}
+
+// copySettings returns a deep copy of the nested settings maps.
+func copySettings(settings map[string]any) map[string]any {
+	copied := make(map[string]any, len(settings))
+	for key, value := range settings {
+		if sub, ok := value.(map[string]any); ok {
+			value = copySettings(sub)
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+// migrateSettings upgrades the config file settings in place to the current schema version.
+// It returns the applied migrations.
+func migrateSettings(settings map[string]any) ([]configMigration, error) {
+	version, err := settingsVersion(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []configMigration
+	for _, migration := range configMigrations {
+		if migration.version <= version {
+			continue
+		}
+		migration.migrate(settings)
+		if profiles, ok := settings[config.ProfilesKey].(map[string]any); ok {
+			for _, profile := range profiles {
+				if profileSettings, ok := profile.(map[string]any); ok {
+					migration.migrate(profileSettings)
+				}
+			}
+		}
+		applied = append(applied, migration)
+	}
+	settings[configVersionKey] = configVersion
+	return applied, nil
+}
+
+// moveToProviders returns a migration that moves the provider section, e.g. "openai",
+// into the providers map. Keys already in the providers map are kept.
+func moveToProviders(provider string) func(settings map[string]any) {
+	return func(settings map[string]any) {
+		section, ok := settings[provider].(map[string]any)
+		if !ok {
+			return
+		}
+		providers, ok := settings[providersKey].(map[string]any)
+		if !ok {
+			providers = make(map[string]any)
+			settings[providersKey] = providers
+		}
+		moved, ok := providers[provider].(map[string]any)
+		if !ok {
+			moved = make(map[string]any)
+			providers[provider] = moved
+		}
+		for key, value := range section {
+			if _, exists := moved[key]; !exists {
+				moved[key] = value
+			}
+		}
+		delete(settings, provider)
+	}
+}
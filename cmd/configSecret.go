@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// configSecretCmd represents the config secret command
+var configSecretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage secrets in the encrypted keystore",
+	Long: `Manage secrets in the encrypted keystore.
+The keystore is protected with a passphrase, which is read from the terminal
+or from the AAI_KEYSTORE_PASSPHRASE environment variable. The passphrase of a new
+keystore is asked twice.
+
+Config values can reference secrets instead of storing them in plain text:
+	keystore:openai           secret from the keystore
+	cmd:pass show openai      output of a shell command
+	file:/run/secrets/openai  content of a file
+	env:OPENAI_KEY            value of an environment variable
+
+Example:
+	$ aai config secret set openai
+	$ aai config set --openai-apikey keystore:openai
+`,
+}
+
+func init() {
+	configCmd.AddCommand(configSecretCmd)
+}
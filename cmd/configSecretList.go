@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// configSecretListCmd represents the config secret list command
+var configSecretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List names of secrets in the keystore",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keystore, err := openKeystore(cmd.Context(), false)
+		if err != nil {
+			return fmt.Errorf("failed to open keystore: %w", err)
+		}
+		for _, name := range keystore.Names() {
+			fmt.Println(name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configSecretCmd.AddCommand(configSecretListCmd)
+}
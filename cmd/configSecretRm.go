@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/spf13/cobra"
+)
+
+// configSecretRmCmd represents the config secret rm command
+var configSecretRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Remove a secret from the keystore",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoSecretNameArg, "Please provide a secret name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keystore, err := openKeystore(cmd.Context(), false)
+		if err != nil {
+			return fmt.Errorf("failed to open keystore: %w", err)
+		}
+		if err = keystore.Remove(args[0]); err != nil {
+			return errs.New(err, fmt.Sprintf("There is no secret %q in the keystore", args[0]))
+		}
+		if err = keystore.Save(); err != nil {
+			return fmt.Errorf("failed to save keystore: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configSecretCmd.AddCommand(configSecretRmCmd)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errNoSecretNameArg is returned when no secret name argument is provided
+	errNoSecretNameArg = errors.New("no secret name argument provided")
+)
+
+// configSecretSetCmd represents the config secret set command
+var configSecretSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Store a secret in the keystore",
+	Long: `Store a secret in the keystore.
+The secret is read from the terminal without echo, or from stdin if it is piped.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoSecretNameArg, "Please provide a secret name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		var secret string
+		name := args[0]
+
+		if isPiped(cmd.InOrStdin()) {
+			secret, err = readAll(cmd.InOrStdin())
+		} else {
+			secret, err = readPassword(fmt.Sprintf("Secret %s: ", name))
+		}
+		if err != nil {
+			return err
+		}
+		if secret == "" {
+			return errs.New(secrets.ErrEmptySecret, "The secret is empty")
+		}
+
+		keystore, err := openKeystore(cmd.Context(), true)
+		if err != nil {
+			return fmt.Errorf("failed to open keystore: %w", err)
+		}
+		keystore.Set(name, secret)
+		if err = keystore.Save(); err != nil {
+			return fmt.Errorf("failed to save keystore: %w", err)
+		}
+
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Secret stored in %s, reference it with %s%s\n", keystore.Path(), secrets.KeystoreScheme, name)
+		return nil
+	},
+}
+
+func init() {
+	configSecretCmd.AddCommand(configSecretSetCmd)
+}
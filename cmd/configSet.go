@@ -3,68 +3,104 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"strings"
+
 	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
 	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"path/filepath"
+)
+
+var (
+	// errInvalidKeyValue is returned when a positional argument is not in the key=value form
+	errInvalidKeyValue = errors.New("invalid key=value argument")
 )
 
 // configSetCmd represents the set command
 var configSetCmd = &cobra.Command{
-	Use:   "set",
+	Use:   "set [key=value]...",
 	Short: "Set config values in config file using flags",
+	Long: `Set config values in config file using flags or key=value arguments.
+Values of key=value arguments use the same syntax as flags.
+If a profile is selected with --profile, the values are set in that profile.
+
+Example:
+	$ aai config set --openai-model text-davinci-003
+	$ aai config set providers.openai.timeout=30s redact.rules=host=corp\.example\.com
+`,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var err error
 		cfg := GetGlobalConfig(cmd.Context())
-		changes := false
 
-		err = config.Traverse(globalConfig, func(value config.AnyValue) error {
-			if value.IsSet() {
-				value.SetAny(value.GetAny())
-				changes = true
-			}
-			return nil
-		})
+		changes, err := flagChanges()
 		if err != nil {
-			return fmt.Errorf("failed to traverse config: %w", err)
+			return err
+		}
+		for _, arg := range args {
+			key, value, err := parseKeyValue(arg)
+			if err != nil {
+				return err
+			}
+			changes[key] = value
 		}
-		if !changes {
+		if len(changes) == 0 {
 			// nothing to do
 			return nil
 		}
 
-		// Write config to file
+		var file string
 		if configSetCmdConfig.File.Changed() {
-			// Write to file specified by flag, create directories if needed
-			file := configSetCmdConfig.File.Get()
-			fs := GetFs(cmd.Context())
-			if err = fs.MkdirAll(filepath.Dir(file), 0755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(file), err)
-			}
-			err = cfg.WriteConfigAs(configSetCmdConfig.File.Get())
-		} else if cfg.ConfigFileUsed() != "" {
-			err = cfg.WriteConfig()
-		} else {
-			return errors.New("no config file to write to")
+			// Write to file specified by flag
+			file = configSetCmdConfig.File.Get()
+		} else if file, err = usedConfigFile(cfg); err != nil {
+			return err
 		}
 
-		if err != nil {
-			var notFoundErr viper.ConfigFileNotFoundError
-			if errors.As(err, &notFoundErr) {
-				return fmt.Errorf("Error: Config file does not exist.\nUse --create to create it in the default path %s\nUse --create and --config to specify custom config file destination\n\n%w", firstDefaultConfigFile, err)
-			}
-			return fmt.Errorf("failed to write config: %w", err)
+		if apiKey, ok := changes[globalConfig.ApiKey.Key()].(string); ok && apiKey != "" && !secrets.IsReference(apiKey) {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "warning: the api key is stored in plain text, consider storing it with \"aai config secret set\" and a keystore: reference")
+		}
+
+		profile := ""
+		if globalConfig.Profile.Flag().Changed {
+			profile = globalConfig.Profile.Get()
 		}
 
+		err = updateConfigFile(GetFs(cmd.Context()), file, func(fileConfig *viper.Viper) error {
+			for key, value := range changes {
+				if profile != "" {
+					key = config.ProfileKey(profile, key)
+				}
+				fileConfig.Set(key, value)
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("failed to traverse config: %w", err)
+			return fmt.Errorf("failed to write config: %w", err)
 		}
 		return nil
 	},
 }
 
+// parseKeyValue parses a key=value argument into the config key and its typed value.
+func parseKeyValue(arg string) (string, any, error) {
+	key, text, ok := strings.Cut(arg, "=")
+	if !ok {
+		return "", nil, errs.New(errInvalidKeyValue, fmt.Sprintf("Invalid argument %q, expected key=value", arg))
+	}
+	value, err := lookupValue(key)
+	if err != nil {
+		return "", nil, err
+	}
+	parsed, err := value.ParseAny(text)
+	if err != nil {
+		return "", nil, errs.New(err, fmt.Sprintf("Invalid value: %v", err))
+	}
+	return value.Key(), fileValue(parsed), nil
+}
+
 type ConfigSetCmdConfig struct {
 	File flags.Flag[string]
 }
@@ -78,7 +114,7 @@ func init() {
 	configSetCmdConfig = ConfigSetCmdConfig{
 		File: flags.StringP(configSetCmd.Flags(), "file", "f", "", "file to write config to"),
 	}
-	if err = configSetCmd.MarkFlagFilename("file", "yaml"); err != nil {
+	if err = configSetCmd.MarkFlagFilename("file", configFileTypes...); err != nil {
 		panic(err)
 	}
 }
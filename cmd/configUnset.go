@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a config key from the config file",
+	Long: `Remove a config key from the config file, so that its default value is used.
+If a profile is selected with --profile, the key is removed from that profile.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoKeyArg, "Please provide a config key")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := GetGlobalConfig(cmd.Context())
+
+		value, err := lookupValue(args[0])
+		if err != nil {
+			return err
+		}
+		key := value.Key()
+		if globalConfig.Profile.Flag().Changed {
+			key = config.ProfileKey(globalConfig.Profile.Get(), key)
+		}
+
+		file, err := usedConfigFile(cfg)
+		if err != nil {
+			return err
+		}
+		err = updateConfigFile(GetFs(cmd.Context()), file, func(fileConfig *viper.Viper) error {
+			if !fileConfig.IsSet(key) {
+				return errs.New(errUnknownKey, fmt.Sprintf("Key %q is not set in %s", key, file))
+			}
+			return unsetKey(fileConfig, key)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configUnsetCmd)
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// errInvalidConfig is returned when the config file has invalid or unknown keys
+	errInvalidConfig = errors.New("invalid config")
+	// errUnknownKey is returned for config file keys that are not used by aai
+	errUnknownKey = errors.New("unknown key")
+)
+
+// configProblem is an invalid or unknown key in the config file.
+type configProblem struct {
+	line int
+	err  error
+}
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config files for invalid and unknown keys",
+	Long: `Check the config files for invalid and unknown keys.
+Every problem is reported with its location in the file, including values in profiles.
+All merged config files are checked, unless a file is selected with --file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fs := GetFs(cmd.Context())
+
+		var files []string
+		if configValidateCmdConfig.File.Changed() {
+			files = []string{configValidateCmdConfig.File.Get()}
+		} else {
+			for _, file := range configFiles {
+				files = append(files, file.path)
+			}
+		}
+		if len(files) == 0 {
+			return errs.New(errNoConfigFile, "No config file found")
+		}
+
+		count := 0
+		for _, file := range files {
+			problems, err := validateConfigFile(fs, file)
+			if err != nil {
+				return err
+			}
+			for _, problem := range problems {
+				fmt.Printf("%s:%d: %v\n", file, problem.line, problem.err)
+			}
+			if len(problems) == 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s is valid\n", file)
+			}
+			count += len(problems)
+		}
+		if count > 0 {
+			return errs.New(errInvalidConfig, fmt.Sprintf("Found %d problems", count))
+		}
+		return nil
+	},
+}
+
+// validateConfigFile returns the invalid and unknown keys of the config file, sorted by line.
+func validateConfigFile(fs afero.Fs, file string) ([]configProblem, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+	lines, err := keyLines(data, configType(file))
+	if err != nil {
+		return nil, errs.New(err, fmt.Sprintf("Cannot parse %s: %v", file, err))
+	}
+
+	fileConfig, err := readConfigFile(fs, file)
+	if err != nil {
+		return nil, err
+	}
+	// The keys of older versions are not validated, they are checked after the migration.
+	// Files without the version key report the problem in the first line.
+	versionLine := lines[configVersionKey]
+	if versionLine == 0 {
+		versionLine = 1
+	}
+	version, outdated, err := outdatedSettings(fileConfig.AllSettings())
+	if err != nil {
+		return []configProblem{{line: versionLine, err: err}}, nil
+	}
+	if outdated {
+		err = fmt.Errorf("%w %d, run \"aai config migrate\"", errOutdatedConfig, version)
+		return []configProblem{{line: versionLine, err: err}}, nil
+	}
+
+	values, err := configValues()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []configProblem
+	// validated are the validated keys, map values have a key for each of their entries.
+	validated := make(map[string]bool)
+	for _, key := range fileConfig.AllKeys() {
+		if key == configVersionKey {
+			continue
+		}
+		valueKey, mapKey := mapValueKey(values, profileValueKey(key)), mapValueKey(values, key)
+		value, ok := values[valueKey]
+		if !ok {
+			problems = append(problems, configProblem{line: lines[key], err: fmt.Errorf("%w %s", errUnknownKey, key)})
+			continue
+		}
+		if validated[mapKey] {
+			continue
+		}
+		validated[mapKey] = true
+		if err = value.ValidateIn(fileConfig, mapKey); err != nil {
+			problems = append(problems, configProblem{line: lines[mapKey], err: err})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].line < problems[j].line
+	})
+	return problems, nil
+}
+
+// mapValueKey returns the key of the map value that contains the key, e.g. "redact.rules"
+// for "redact.rules.hostname". Other keys are returned unchanged.
+func mapValueKey(values map[string]config.AnyValue, key string) string {
+	for prefix := key; strings.Contains(prefix, "."); {
+		prefix = prefix[:strings.LastIndex(prefix, ".")]
+		if _, ok := values[profileValueKey(prefix)]; ok {
+			return prefix
+		}
+	}
+	return key
+}
+
+// keyLines returns the line of every key in the config document of the type, by its lowercase
+// dotted path, e.g. "providers.openai.model". Keys are lowercase, since viper keys are case-insensitive.
+func keyLines(data []byte, configType string) (map[string]int, error) {
+	if configType == "toml" {
+		return tomlKeyLines(data)
+	}
+	// JSON is a subset of YAML
+	return yamlKeyLines(data)
+}
+
+// yamlKeyLines returns the line of every key in the YAML document.
+func yamlKeyLines(data []byte) (map[string]int, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	lines := make(map[string]int)
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, prefix)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := strings.ToLower(prefix + node.Content[i].Value)
+				lines[key] = node.Content[i].Line
+				walk(node.Content[i+1], key+".")
+			}
+		}
+	}
+	walk(&root, "")
+	return lines, nil
+}
+
+// tomlKeyLines returns the line of every key in the TOML document. The document is scanned line by line
+// for table headers and key/value pairs, which covers the documents written by aai.
+func tomlKeyLines(data []byte) (map[string]int, error) {
+	var document map[string]any
+	if err := toml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	lines := make(map[string]int)
+	// add sets the line of the key and of its parent tables, if they are not set yet.
+	add := func(key string, line int) {
+		for prefix := key; prefix != ""; {
+			if _, ok := lines[prefix]; !ok {
+				lines[prefix] = line
+			}
+			i := strings.LastIndex(prefix, ".")
+			if i < 0 {
+				break
+			}
+			prefix = prefix[:i]
+		}
+	}
+
+	table := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "["):
+			table = tomlKey(strings.Trim(line, "[] "))
+			add(table, i+1)
+		case strings.Contains(line, "="):
+			key := tomlKey(line[:strings.Index(line, "=")])
+			if table != "" {
+				key = table + "." + key
+			}
+			add(key, i+1)
+		}
+	}
+	return lines, nil
+}
+
+// tomlKey returns the lowercase dotted form of the TOML key, without quotes and spaces.
+func tomlKey(key string) string {
+	key = strings.NewReplacer(`"`, "", "'", "", " ", "", "\t", "").Replace(key)
+	return strings.ToLower(key)
+}
+
+type ConfigValidateCmdConfig struct {
+	File flags.Flag[string]
+}
+
+var configValidateCmdConfig ConfigValidateCmdConfig
+
+func init() {
+	var err error
+	configCmd.AddCommand(configValidateCmd)
+
+	configValidateCmdConfig = ConfigValidateCmdConfig{
+		File: flags.StringP(configValidateCmd.Flags(), "file", "f", "", "file to validate, all merged config files by default"),
+	}
+	if err = configValidateCmd.MarkFlagFilename("file", configFileTypes...); err != nil {
+		panic(err)
+	}
+}
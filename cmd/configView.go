@@ -2,40 +2,226 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 // configViewCmd represents the configView command
 var configViewCmd = &cobra.Command{
 	Use:   "view",
 	Short: "Display config",
+	Long: `Display the config file.
+With --effective, display the resolved value of every config key and its source:
+a flag, an environment variable, a profile, the config file or the default value.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var err error
 
 		ctx := cmd.Context()
-		globalConfig := GetGlobalConfig(ctx)
+		cfg := GetGlobalConfig(ctx)
+
+		output := configViewCmdConfig.Output.Get()
+		if output != outputYaml && output != outputJson && output != outputToml {
+			return errs.New(errUnknownOutput, fmt.Sprintf("Unknown output format %q, use %q, %q or %q", output, outputYaml, outputJson, outputToml))
+		}
+		if configViewCmdConfig.Effective.Get() {
+			return printEffectiveConfig(output)
+		}
 
-		if globalConfig.ConfigFileUsed() == "" {
+		if cfg.ConfigFileUsed() == "" {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "No config file found, use --effective to display the default values")
 			return nil
 		}
 
 		readonlyConfig := viper.New()
-		readonlyConfig.SetConfigFile(globalConfig.ConfigFileUsed())
+		readonlyConfig.SetFs(GetFs(ctx))
+		readonlyConfig.SetConfigFile(cfg.ConfigFileUsed())
 		if err = readonlyConfig.ReadInConfig(); err != nil {
 			return fmt.Errorf("failed to read config file: %w", err)
 		}
 
-		fmt.Print(configToString(readonlyConfig))
+		if !rootCmdConfig.ShowSecrets.Get() {
+			if readonlyConfig, err = maskSecrets(readonlyConfig); err != nil {
+				return err
+			}
+		}
+		switch output {
+		case outputJson:
+			return printJson(readonlyConfig.AllSettings())
+		case outputToml:
+			return printToml(readonlyConfig.AllSettings())
+		default:
+			fmt.Print(configToString(readonlyConfig))
+		}
 		return nil
 	},
 }
 
+type ConfigViewCmdConfig struct {
+	Effective flags.Flag[bool]
+	Output    flags.Flag[string]
+}
+
+var configViewCmdConfig ConfigViewCmdConfig
+
 func init() {
 	configCmd.AddCommand(configViewCmd)
+
+	configViewCmdConfig = ConfigViewCmdConfig{
+		Effective: flags.Bool(configViewCmd.Flags(), "effective", false, "display the resolved values of all keys and their sources"),
+		Output:    flags.StringP(configViewCmd.Flags(), "output", "o", outputYaml, "output format (yaml|json|toml)"),
+	}
+}
+
+// effectiveValue is the resolved value of a config key and its source.
+type effectiveValue struct {
+	Value  any    `json:"value" toml:"value"`
+	Source string `json:"source" toml:"source"`
+}
+
+// printEffectiveConfig prints the resolved values of the global config with their sources.
+// In the YAML output, the sources are comments next to the values.
+func printEffectiveConfig(output string) error {
+	profile := globalConfig.Profile.Get()
+	tree := make(map[string]any)
+	err := config.Traverse(&globalConfig, func(value config.AnyValue) error {
+		resolved := fileValue(value.GetAny())
+		if value.IsSecret() && !rootCmdConfig.ShowSecrets.Get() {
+			resolved = secrets.Mask(fmt.Sprint(resolved))
+		}
+		setNested(tree, strings.Split(value.Key(), "."), effectiveValue{
+			Value:  resolved,
+			Source: sourceName(value, profile),
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to traverse config: %w", err)
+	}
+
+	switch output {
+	case outputJson:
+		return printJson(tree)
+	case outputToml:
+		return printToml(tree)
+	}
+	node, err := effectiveNode(tree)
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(node); err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+	return encoder.Close()
+}
+
+// sourceName returns the displayed name of the value source, with the file path or the profile name.
+func sourceName(value config.AnyValue, profile string) string {
+	source := value.Source(profile)
+	switch source {
+	case config.SourceFile:
+		return configFileOf(value.Key())
+	case config.SourceProfile:
+		return fmt.Sprintf("%s %s", source, profile)
+	}
+	return string(source)
+}
+
+// setNested sets the value in the nested map under the key path.
+func setNested(tree map[string]any, path []string, value any) {
+	for _, key := range path[:len(path)-1] {
+		sub, ok := tree[key].(map[string]any)
+		if !ok {
+			sub = make(map[string]any)
+			tree[key] = sub
+		}
+		tree = sub
+	}
+	tree[path[len(path)-1]] = value
+}
+
+// effectiveNode returns the YAML node of the effective config tree, with keys sorted
+// and the sources of the values as line comments.
+func effectiveNode(tree map[string]any) (*yaml.Node, error) {
+	keys := make([]string, 0, len(tree))
+	for key := range tree {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: key}
+		valueNode := &yaml.Node{}
+		switch value := tree[key].(type) {
+		case map[string]any:
+			sub, err := effectiveNode(value)
+			if err != nil {
+				return nil, err
+			}
+			valueNode = sub
+		case effectiveValue:
+			if err := valueNode.Encode(value.Value); err != nil {
+				return nil, fmt.Errorf("failed to marshal %s: %w", key, err)
+			}
+			// yaml.v3 emits the line comments of block sequences and mappings on the following nodes,
+			// so their sources are comments of the keys. Empty ones are written inline, e.g. [].
+			if valueNode.Kind == yaml.ScalarNode || len(valueNode.Content) == 0 {
+				valueNode.LineComment = value.Source
+			} else {
+				keyNode.LineComment = value.Source
+			}
+		}
+		node.Content = append(node.Content, keyNode, valueNode)
+	}
+	return node, nil
+}
+
+// maskSecrets returns a copy of the config with the secret values masked.
+// Secret values in profiles are masked as well.
+func maskSecrets(cfg *viper.Viper) (*viper.Viper, error) {
+	secretKeys := make(map[string]bool)
+	err := config.Traverse(&globalConfig, func(value config.AnyValue) error {
+		if value.IsSecret() {
+			secretKeys[value.Key()] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find secret values: %w", err)
+	}
+
+	masked := viper.New()
+	for _, key := range cfg.AllKeys() {
+		value := cfg.Get(key)
+		// Deprecated keys, such as openai.apikey, are aliases of the current secret keys.
+		if current, _ := currentKey(profileValueKey(key)); secretKeys[current] {
+			value = secrets.Mask(cfg.GetString(key))
+		}
+		masked.Set(key, value)
+	}
+	return masked, nil
+}
+
+// profileValueKey returns the key of the value inside a profile, e.g. "profiles.fast.providers.openai.apikey"
+// returns "providers.openai.apikey". Keys outside of profiles are returned unchanged.
+func profileValueKey(key string) string {
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) == 3 && parts[0] == config.ProfilesKey {
+		return parts[2]
+	}
+	return key
 }
 
 // configToString returns a string representation of the config
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"context"
+	"net/http"
+
 	"github.com/spf13/afero"
 
 	"github.com/spf13/viper"
@@ -25,3 +27,16 @@ func GetFs(ctx context.Context) afero.Fs {
 func SetFs(ctx context.Context, fs afero.Fs) context.Context {
 	return context.WithValue(ctx, FileSystemKey, fs)
 }
+
+const TransportKey = "transport"
+
+// GetTransport returns the transport of the provider requests, or nil if the default transport is used.
+func GetTransport(ctx context.Context) http.RoundTripper {
+	transport, _ := ctx.Value(TransportKey).(http.RoundTripper)
+	return transport
+}
+
+// SetTransport sets the transport of the provider requests, e.g. a cassette in tests.
+func SetTransport(ctx context.Context, transport http.RoundTripper) context.Context {
+	return context.WithValue(ctx, TransportKey, transport)
+}
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/openai"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// checkTimeout is the timeout of a single network check.
+	checkTimeout = 5 * time.Second
+	// maxClockSkew is the maximum difference between the local and the server time.
+	maxClockSkew = time.Minute
+)
+
+var (
+	// errChecksFailed is returned when some of the doctor checks fail
+	errChecksFailed = errors.New("doctor checks failed")
+)
+
+// checkStatus is the status of a doctor check.
+type checkStatus string
+
+const (
+	checkPass checkStatus = "PASS"
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "SKIP"
+)
+
+// checkResult is the result of a doctor check.
+type checkResult struct {
+	status checkStatus
+	// detail describes the result.
+	detail string
+	// hint is the remediation of a failed check.
+	hint string
+}
+
+// doctorCheck is a single diagnostic check.
+type doctorCheck struct {
+	name string
+	run  func(cmd *cobra.Command) checkResult
+}
+
+// doctorChecks are the checks run by the doctor command, in order.
+var doctorChecks = []doctorCheck{
+	{name: "config files", run: checkConfigFiles},
+	{name: "api key", run: checkApiKey},
+	{name: "proxy", run: checkProxy},
+	{name: "dns", run: checkDns},
+	{name: "connection", run: checkConnection},
+	{name: "clock", run: checkClock},
+	{name: "model", run: checkModel},
+	{name: "shell integration", run: checkShellIntegration},
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the aai setup",
+	Long: `Diagnose the aai setup. The doctor checks the config files, the API keys
+of the providers, the proxy settings, the DNS and TLS connection to the provider,
+the clock, the model availability and the shell integration.
+The DNS and connection checks are skipped when a proxy is used, the proxy connects
+to the provider instead. Every failed check has a hint how to fix it.`,
+	Args: cobra.NoArgs,
+	// The report explains the failures, the usage would only hide it
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := 0
+		for _, check := range doctorChecks {
+			result := check.run(cmd)
+			fmt.Printf("[%s] %s: %s\n", result.status, check.name, result.detail)
+			if result.hint != "" && (result.status == checkFail || result.status == checkWarn) {
+				fmt.Printf("       hint: %s\n", result.hint)
+			}
+			if result.status == checkFail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return errs.New(errChecksFailed, fmt.Sprintf("%d checks failed", failed))
+		}
+		return nil
+	},
+}
+
+func checkConfigFiles(cmd *cobra.Command) checkResult {
+	fs := GetFs(cmd.Context())
+	paths, _, err := configFilePaths(fs)
+	if err != nil {
+		return checkResult{status: checkFail, detail: err.Error()}
+	}
+
+	var found, outdated []string
+	problems := 0
+	for _, path := range paths {
+		if exists, _ := afero.Exists(fs, path); !exists {
+			continue
+		}
+		found = append(found, path)
+
+		fileConfig, err := readConfigFile(fs, path)
+		if err != nil {
+			return checkResult{status: checkFail, detail: err.Error(), hint: fmt.Sprintf("fix the syntax of %s", path)}
+		}
+		// Outdated files are still read, they are validated after the migration.
+		if _, isOutdated, err := outdatedSettings(fileConfig.AllSettings()); err == nil && isOutdated {
+			outdated = append(outdated, path)
+			continue
+		}
+		fileProblems, err := validateConfigFile(fs, path)
+		if err != nil {
+			return checkResult{status: checkFail, detail: err.Error()}
+		}
+		problems += len(fileProblems)
+	}
+
+	if len(found) == 0 {
+		return checkResult{status: checkWarn, detail: fmt.Sprintf("no config file found in %s", strings.Join(paths, ", ")), hint: `run "aai init"`}
+	}
+	if problems > 0 {
+		return checkResult{status: checkFail, detail: fmt.Sprintf("%d problems in %s", problems, strings.Join(found, ", ")), hint: `run "aai config validate" for details`}
+	}
+	if len(outdated) > 0 {
+		return checkResult{status: checkWarn, detail: fmt.Sprintf("deprecated keys in %s", strings.Join(outdated, ", ")), hint: `run "aai config migrate"`}
+	}
+	return checkResult{status: checkPass, detail: strings.Join(found, ", ")}
+}
+
+// checkApiKey reports the API key of every provider that needs one. Missing or invalid keys
+// fail the check only for the used providers.
+func checkApiKey(cmd *cobra.Command) checkResult {
+	ctx := cmd.Context()
+	used := usedProviders()
+	result := checkResult{status: checkSkip}
+	var details []string
+	for _, name := range providerNames() {
+		apiKey, ok := providerApiKey(name)
+		if !ok {
+			continue
+		}
+		var detail, hint string
+		if apiKey.Get() == "" {
+			detail = fmt.Sprintf("%s is not set", apiKey.Key())
+			hint = fmt.Sprintf(`run "aai init" or set the %s environment variable`, config.EnvName(apiKey.Key()))
+		} else if _, err := newSecretResolver(ctx).Resolve(ctx, apiKey.Get()); err != nil {
+			detail = err.Error()
+			hint = fmt.Sprintf("check the secret reference in %s", apiKey.Key())
+		} else {
+			detail = fmt.Sprintf("%s is set (%s)", apiKey.Key(), sourceName(apiKey.(config.AnyValue), globalConfig.Profile.Get()))
+		}
+		switch {
+		case !used[name]:
+			detail += ", not used"
+		case hint != "" && result.status != checkFail:
+			result.status, result.hint = checkFail, hint
+		case result.status == checkSkip:
+			result.status = checkPass
+		}
+		details = append(details, fmt.Sprintf("%s: %s", name, detail))
+	}
+	if len(details) == 0 {
+		return checkResult{status: checkSkip, detail: "no provider needs a key"}
+	}
+	result.detail = strings.Join(details, "; ")
+	return result
+}
+
+// usedProviders returns the selected provider and the providers of its fallback chain.
+func usedProviders() map[string]bool {
+	provider := globalConfig.Provider.Get()
+	used := map[string]bool{provider: true}
+	if provider == fallbackProvider {
+		for _, name := range globalConfig.FallbackProviders.Get() {
+			used[name] = true
+		}
+	}
+	return used
+}
+
+func checkProxy(cmd *cobra.Command) checkResult {
+	endpoint, result := endpointUrl()
+	if endpoint == nil {
+		return result
+	}
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: endpoint})
+	if err != nil {
+		return checkResult{status: checkFail, detail: fmt.Sprintf("invalid proxy settings: %v", err), hint: "check the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables"}
+	}
+	if proxy == nil {
+		return checkResult{status: checkPass, detail: "no proxy"}
+	}
+	conn, err := net.DialTimeout("tcp", hostPort(proxy), checkTimeout)
+	if err != nil {
+		return checkResult{status: checkFail, detail: fmt.Sprintf("proxy %s is not reachable: %v", proxy.Redacted(), err), hint: "check the HTTPS_PROXY and HTTP_PROXY environment variables"}
+	}
+	_ = conn.Close()
+	return checkResult{status: checkPass, detail: fmt.Sprintf("using proxy %s", proxy.Redacted())}
+}
+
+func checkDns(cmd *cobra.Command) checkResult {
+	endpoint, result := endpointUrl()
+	if endpoint == nil {
+		return result
+	}
+	if proxy := endpointProxy(endpoint); proxy != nil {
+		return checkResult{status: checkSkip, detail: fmt.Sprintf("the host is resolved by the proxy %s", proxy.Redacted())}
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), checkTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, endpoint.Hostname())
+	if err != nil {
+		return checkResult{status: checkFail, detail: err.Error(), hint: "check your network connection and DNS settings"}
+	}
+	return checkResult{status: checkPass, detail: fmt.Sprintf("%s resolves to %s", endpoint.Hostname(), strings.Join(addrs, ", "))}
+}
+
+func checkConnection(cmd *cobra.Command) checkResult {
+	endpoint, result := endpointUrl()
+	if endpoint == nil {
+		return result
+	}
+	// The clock and model checks send requests through the proxy.
+	if proxy := endpointProxy(endpoint); proxy != nil {
+		return checkResult{status: checkSkip, detail: fmt.Sprintf("connecting through the proxy %s", proxy.Redacted())}
+	}
+	address := hostPort(endpoint)
+	dialer := &net.Dialer{Timeout: checkTimeout}
+	if endpoint.Scheme != "https" {
+		conn, err := dialer.Dial("tcp", address)
+		if err != nil {
+			return checkResult{status: checkFail, detail: err.Error(), hint: fmt.Sprintf("check that the server at %s is running", endpoint)}
+		}
+		_ = conn.Close()
+		return checkResult{status: checkPass, detail: fmt.Sprintf("connected to %s", address)}
+	}
+
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: endpoint.Hostname()})
+	if err != nil {
+		return checkResult{status: checkFail, detail: err.Error(), hint: "check your network connection, firewall and system certificates"}
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return checkResult{status: checkPass, detail: fmt.Sprintf("TLS connection to %s", address)}
+	}
+	return checkResult{status: checkPass, detail: fmt.Sprintf("TLS connection to %s, certificate valid until %s", address, certs[0].NotAfter.Format("2006-01-02"))}
+}
+
+func checkClock(cmd *cobra.Command) checkResult {
+	endpoint, result := endpointUrl()
+	if endpoint == nil {
+		return result
+	}
+	client := &http.Client{Timeout: checkTimeout}
+	res, err := client.Head(endpoint.String())
+	if err != nil {
+		return checkResult{status: checkFail, detail: err.Error(), hint: "fix the connection checks first"}
+	}
+	_ = res.Body.Close()
+	date, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return checkResult{status: checkWarn, detail: "the server did not send its time"}
+	}
+	skew := time.Since(date).Round(time.Second)
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		return checkResult{status: checkFail, detail: fmt.Sprintf("the local clock differs from the server by %s", skew), hint: "synchronize the system clock, e.g. enable NTP"}
+	}
+	return checkResult{status: checkPass, detail: fmt.Sprintf("the local clock differs from the server by %s", skew)}
+}
+
+// modelChecker is implemented by providers that can check the model availability.
+type modelChecker interface {
+	CheckModel() error
+}
+
+func checkModel(cmd *cobra.Command) checkResult {
+	provider, err := newProvider(cmd.Context(), globalConfig.Provider.Get(), providerOptions{})
+	if err != nil {
+		return checkResult{status: checkFail, detail: err.Error(), hint: "fix the api key check first"}
+	}
+	checker, ok := provider.(modelChecker)
+	if !ok {
+		return checkResult{status: checkSkip, detail: "the provider cannot check the model"}
+	}
+	err = checker.CheckModel()
+	switch {
+	case err == nil:
+		return checkResult{status: checkPass, detail: fmt.Sprintf("%s is available", globalConfig.Model.Get())}
+	case errors.Is(err, openai.ErrModelNotFound):
+		return checkResult{status: checkFail, detail: err.Error(), hint: "set an available model with \"aai config set --openai-model\""}
+	case errors.Is(err, openai.ErrUnauthorized):
+		return checkResult{status: checkFail, detail: err.Error(), hint: "check the api key, or create a new one"}
+	}
+	return checkResult{status: checkFail, detail: err.Error(), hint: "fix the connection checks first"}
+}
+
+func checkShellIntegration(cmd *cobra.Command) checkResult {
+	dialect := shell.Dialect(globalConfig.Shell.Get())
+	file, err := rcFile(dialect)
+	if err != nil {
+		return checkResult{status: checkSkip, detail: fmt.Sprintf("not available for %s", dialect)}
+	}
+	installed, current, err := shellIntegrationInstalled(GetFs(cmd.Context()), dialect)
+	if err != nil {
+		return checkResult{status: checkFail, detail: err.Error()}
+	}
+	if !installed {
+		return checkResult{status: checkWarn, detail: fmt.Sprintf("not installed in %s", file), hint: `run "aai init" to install it`}
+	}
+	if !current {
+		return checkResult{status: checkWarn, detail: fmt.Sprintf("outdated in %s", file), hint: `run "aai init" to update it`}
+	}
+	return checkResult{status: checkPass, detail: fmt.Sprintf("installed in %s", file)}
+}
+
+// endpointUrl returns the parsed provider endpoint, or nil and the result
+// of a check that cannot be run.
+func endpointUrl() (*url.URL, checkResult) {
+	endpoint, ok := providerEndpoint(globalConfig.Provider.Get())
+	if !ok {
+		return nil, checkResult{status: checkSkip, detail: "the provider does not use the network"}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, checkResult{status: checkFail, detail: fmt.Sprintf("invalid endpoint %q", endpoint), hint: fmt.Sprintf("fix %s in the config", globalConfig.BaseUrl.Key())}
+	}
+	return u, checkResult{}
+}
+
+// endpointProxy returns the proxy used to connect to the endpoint, or nil if there is none
+// or the proxy settings are invalid, which is reported by the proxy check.
+func endpointProxy(endpoint *url.URL) *url.URL {
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: endpoint})
+	if err != nil {
+		return nil
+	}
+	return proxy
+}
+
+// hostPort returns the host and port of the URL, with the default port of its scheme.
+func hostPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return net.JoinHostPort(u.Hostname(), port)
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/eval"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errNoSuiteArg is returned when no suite file is provided
+	errNoSuiteArg = errors.New("no suite argument provided")
+	// errRegressions is returned when cases that passed in the baseline fail
+	errRegressions = errors.New("regressions found")
+)
+
+// evalCmd represents the eval command
+var evalCmd = &cobra.Command{
+	Use:   "eval <suite.yaml>",
+	Short: "Evaluate providers and models with a suite of queries",
+	Long: `Evaluate providers and models with a suite of queries.
+Every query of the suite is sent to the targets, and the suggested commands are checked.
+The accuracy, the average latency, the tokens and the estimated cost of every target are reported.
+
+A case can check that the command is the expected one (expect), matches a regular expression (match),
+is a valid bash command (parses), runs programs (uses), or prints the expected output
+in a temporary directory with the given files (sandbox). Sandbox checks execute the suggested
+commands, so they are skipped unless --sandbox is set. The commands run with bash, with only PATH,
+LANG and HOME (the temporary directory) in their environment, and are killed after 10 seconds.
+The sandbox gives no isolation: the commands can read, change and delete any file of the user
+and access the network, so use --sandbox only in a disposable environment, such as a container.
+
+    targets: [openai:text-davinci-003, openai:gpt-3.5-turbo-instruct]
+    cases:
+      - query: count lines in all go files
+        match: 'wc -l'
+        uses: [find, wc]
+      - query: print the names of txt files sorted
+        sandbox:
+          files: {b.txt: "", a.txt: ""}
+          output: "a.txt\nb.txt"
+
+The targets of the suite are used unless --targets is set. Save the report with --save-baseline
+and compare later runs with --baseline, cases that passed in the baseline and fail now are reported
+as regressions.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoSuiteArg, "Please provide a suite file")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		fs := GetFs(ctx)
+
+		output := evalCmdConfig.Output.Get()
+		if output != outputText && output != outputJson {
+			return errs.New(errUnknownOutput, fmt.Sprintf("Unknown output format %q, use %q or %q", output, outputText, outputJson))
+		}
+
+		suite, err := eval.Load(fs, args[0])
+		if err != nil {
+			return errs.New(err, fmt.Sprintf("Cannot load the suite: %v", err))
+		}
+		targets, err := suiteTargets(suite)
+		if err != nil {
+			return err
+		}
+		var baseline *eval.Report
+		if evalCmdConfig.Baseline.Changed() {
+			if baseline, err = eval.LoadReport(fs, evalCmdConfig.Baseline.Get()); err != nil {
+				return errs.New(err, fmt.Sprintf("Cannot load the baseline: %v", err))
+			}
+		}
+
+		var results []eval.Result
+		for _, c := range suite.Cases {
+			log.Info().Str("case", c.Name).Msg("running case")
+			opts, err := suggestOptions(cmd, input{Text: c.Query})
+			if err != nil {
+				return err
+			}
+			answers, _ := runTargets(ctx, targets, c.Query, opts, false)
+			for _, answer := range answers {
+				result := eval.Result{
+					Case:      c.Name,
+					Target:    answer.Target,
+					Command:   answer.Command,
+					Error:     answer.Error,
+					LatencyMs: answer.LatencyMs,
+					Tokens:    answer.Tokens,
+					Cost:      answer.Cost,
+				}
+				if answer.err == nil {
+					result.Checks = c.Check(ctx, answer.Command, evalCmdConfig.Sandbox.Get())
+					result.Skipped = eval.Skipped(result.Checks)
+					result.Passed = !result.Skipped && eval.Passed(result.Checks)
+				}
+				results = append(results, result)
+			}
+		}
+
+		report := eval.NewReport(results)
+		if evalCmdConfig.SaveBaseline.Changed() {
+			if err = report.Save(fs, evalCmdConfig.SaveBaseline.Get()); err != nil {
+				return err
+			}
+		}
+		var regressions []eval.Regression
+		if baseline != nil {
+			regressions = report.Regressions(baseline)
+		}
+
+		if output == outputJson {
+			err = printJson(report)
+		} else {
+			err = printReport(report, regressions)
+		}
+		if err != nil {
+			return err
+		}
+		if len(regressions) > 0 {
+			return errs.New(errRegressions, fmt.Sprintf("Found %d regressions", len(regressions)))
+		}
+		return nil
+	},
+}
+
+// suiteTargets returns the targets of the suite, unless they are set with --targets.
+func suiteTargets(suite *eval.Suite) ([]compareTarget, error) {
+	if len(suite.Targets) == 0 || globalConfig.CompareTargets.Flag().Changed {
+		return configuredTargets()
+	}
+	targets := make([]compareTarget, 0, len(suite.Targets))
+	for _, spec := range suite.Targets {
+		target, err := parseTarget(spec)
+		if err != nil {
+			return nil, errs.New(err, fmt.Sprintf("Invalid suite target: %v", err))
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// printReport prints the failed cases, the summary of every target and the regressions.
+func printReport(report *eval.Report, regressions []eval.Regression) error {
+	for _, result := range report.Results {
+		if result.Passed || result.Skipped {
+			continue
+		}
+		fmt.Printf("FAIL %s [%s]: %s\n", result.Case, result.Target, result.Command)
+		if result.Error != "" {
+			fmt.Printf("  error: %s\n", result.Error)
+		}
+		for _, check := range result.Checks {
+			if !check.Passed && !check.Skipped {
+				fmt.Printf("  %s: %s\n", check.Name, check.Detail)
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TARGET\tPASSED\tSKIPPED\tACCURACY\tAVG LATENCY\tTOKENS\tCOST")
+	for _, summary := range report.Summaries {
+		latency := time.Duration(summary.AvgLatencyMs) * time.Millisecond
+		_, _ = fmt.Fprintf(w, "%s\t%d/%d\t%d\t%.1f%%\t%s\t%d\t$%.5f\n", summary.Target, summary.Passed, summary.Cases-summary.Skipped, summary.Skipped, summary.Accuracy*100, latency, summary.Tokens, summary.Cost)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, regression := range regressions {
+		fmt.Printf("REGRESSION %s [%s]: %q passed in the baseline, now: %q\n", regression.Case, regression.Target, regression.Baseline, regression.Current.Command)
+	}
+	return nil
+}
+
+type EvalCmdConfig struct {
+	Output       flags.Flag[string]
+	Sandbox      flags.Flag[bool]
+	Baseline     flags.Flag[string]
+	SaveBaseline flags.Flag[string]
+}
+
+var evalCmdConfig EvalCmdConfig
+
+func init() {
+	var err error
+	rootCmd.AddCommand(evalCmd)
+
+	evalCmdConfig = EvalCmdConfig{
+		Output:       flags.StringP(evalCmd.Flags(), "output", "o", outputText, "output format (text|json)"),
+		Sandbox:      flags.Bool(evalCmd.Flags(), "sandbox", false, "run the suggested commands of sandbox checks in temporary directories, without isolation"),
+		Baseline:     flags.String(evalCmd.Flags(), "baseline", "", "report to compare the results with"),
+		SaveBaseline: flags.String(evalCmd.Flags(), "save-baseline", "", "file to save the report to, to be used as a baseline"),
+	}
+	if err = evalCmd.MarkFlagFilename("baseline", "json"); err != nil {
+		panic(err)
+	}
+	if err = evalCmd.MarkFlagFilename("save-baseline", "json"); err != nil {
+		panic(err)
+	}
+}
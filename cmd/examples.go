@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// examplesCmd represents the examples command
+var examplesCmd = &cobra.Command{
+	Use:   "examples",
+	Short: "Manage few-shot examples used in suggestion prompts",
+	Long: `Manage a personal library of query/command pairs.
+The most relevant examples are included in every suggestion prompt,
+so the AI learns your preferred commands and idioms.`,
+}
+
+func init() {
+	rootCmd.AddCommand(examplesCmd)
+}
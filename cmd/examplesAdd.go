@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/examples"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errNoExampleArgs is returned when the query or command argument is missing
+	errNoExampleArgs = errors.New("no query or command argument provided")
+)
+
+// examplesAddCmd represents the examples add command
+var examplesAddCmd = &cobra.Command{
+	Use:   "add <query> <command>",
+	Short: "Add an example to the library",
+	Long: `Add a query/command pair to the examples library.
+
+Example:
+	$ aai examples add "search text in files" "rg 'text'"
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errs.New(errNoExampleArgs, "Please provide a query and a command")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		library, err := examples.Load(GetFs(cmd.Context()), globalConfig.ExamplesFile.Get())
+		if err != nil {
+			return fmt.Errorf("failed to load examples: %w", err)
+		}
+
+		err = library.Add(examples.Example{
+			Query:   args[0],
+			Command: args[1],
+		})
+		if err != nil {
+			return errs.New(err, "The query and the command must be single, non-empty lines")
+		}
+		if err = library.Save(); err != nil {
+			return fmt.Errorf("failed to save examples: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	examplesCmd.AddCommand(examplesAddCmd)
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/examples"
+
+	"github.com/spf13/cobra"
+)
+
+// examplesListCmd represents the examples list command
+var examplesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List examples in the library",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		library, err := examples.Load(GetFs(cmd.Context()), globalConfig.ExamplesFile.Get())
+		if err != nil {
+			return fmt.Errorf("failed to load examples: %w", err)
+		}
+
+		for i, example := range library.Examples {
+			fmt.Printf("%d\t%s\t%s\n", i, example.Query, example.Command)
+		}
+		return nil
+	},
+}
+
+func init() {
+	examplesCmd.AddCommand(examplesListCmd)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/examples"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errNoExampleIndexArg is returned when no example index argument is provided
+	errNoExampleIndexArg = errors.New("no example index argument provided")
+)
+
+// examplesRmCmd represents the examples rm command
+var examplesRmCmd = &cobra.Command{
+	Use:   "rm <index>",
+	Short: "Remove an example from the library",
+	Long: `Remove an example from the library.
+Use "aai examples list" to see the example indexes.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errs.New(errNoExampleIndexArg, "Please provide an index of the example to remove")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errs.New(err, fmt.Sprintf("Invalid example index: %s", args[0]))
+		}
+
+		library, err := examples.Load(GetFs(cmd.Context()), globalConfig.ExamplesFile.Get())
+		if err != nil {
+			return fmt.Errorf("failed to load examples: %w", err)
+		}
+		if _, err = library.Remove(index); err != nil {
+			return errs.New(err, fmt.Sprintf("There is no example with index %d", index))
+		}
+		if err = library.Save(); err != nil {
+			return fmt.Errorf("failed to save examples: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	examplesCmd.AddCommand(examplesRmCmd)
+}
@@ -4,10 +4,11 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
 	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
-	"github.com/TomaszDomagala/ask-ai-cli/pkg/openai"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/grounding"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -25,34 +26,66 @@ var explainCmd = &cobra.Command{
 Example:
 	$ aai explain "ls -l"
 	List the contents of the current directory in long format
+
+The command can also be piped to stdin:
+	$ history | tail -1 | aai explain
+
+Use --breakdown to explain every part of the command,
+such as pipeline stages, subcommands, flags and arguments:
+	$ aai explain --breakdown "ls -l | wc -l"
+	ls   List directory contents
+	-l   Use a long listing format
+	|    Pass the output of ls to wc
+	wc   Print newline, word, and byte counts
+	-l   Print the newline counts
+
+Use --ground to look up the local man pages of the programs in the command
+and base the explanation on them, with citations. With --ground-help, programs
+without a man page are run with --help.
 `,
-	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) != 1 {
-			return errs.New(errNoCommandArg, "Please provide a command to explain")
-		}
-		return nil
-	},
+	Args: maxOneArg,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var err error
-		var explainer Explainer
 
-		switch globalConfig.Provider.Get() {
-		case "openai":
-			var openaiCfg openai.Config
-			err := config.Decode(globalConfig, &openaiCfg)
+		output := explainCmdConfig.Output.Get()
+		if output != outputText && output != outputJson {
+			return errs.New(errUnknownOutput, fmt.Sprintf("Unknown output format %q, use %q or %q", output, outputText, outputJson))
+		}
+
+		in, err := readInput(cmd, args, explainCmdConfig.InputCmdConfig, errs.New(errNoCommandArg, "Please provide a command to explain"))
+		if err != nil {
+			return err
+		}
+
+		var excerpts []grounding.Excerpt
+		if explainCmdConfig.Ground.Get() {
+			excerpts, err = grounding.Lookup(cmd.Context(), in.Text, globalConfig.GroundHelp.Get())
 			if err != nil {
-				return fmt.Errorf("failed to decode config: %w", err)
+				return errs.New(err, fmt.Sprintf("Cannot look up documentation of the command: %v", err))
 			}
-			explainer = openai.NewClient(openaiCfg)
-		default:
-			return fmt.Errorf("unknown provider: %v", globalConfig.Provider.Get())
+			log.Debug().Int("count", len(excerpts)).Msg("found documentation excerpts")
+		}
+
+		explainer, err := newProvider(cmd.Context(), globalConfig.Provider.Get(), providerOptions{
+			context:   in.Context,
+			grounding: excerpts,
+		})
+		if err != nil {
+			return err
+		}
+
+		command := in.Text
+		if explainCmdConfig.Breakdown.Get() || explainCmdConfig.Tree.Get() {
+			return explainBreakdown(explainer, command, output, excerpts)
 		}
 
-		command := args[0]
 		response, err := explainer.Explain(command)
 		if err != nil {
 			return fmt.Errorf("failed to explain a command: %w", err)
 		}
+		if output == outputJson {
+			return printJson(explanation{Command: command, Explanation: response, Sources: excerpts, Provider: answeredBy(explainer, globalConfig.Provider.Get())})
+		}
 		fmt.Println(response)
 
 		return nil
@@ -62,10 +95,31 @@ Example:
 type Explainer interface {
 	// Explain returns an explanation for a given command.
 	Explain(command string) (string, error)
+	// ExplainParts returns an explanation for every part of a given command.
+	ExplainParts(command string, parts []string) ([]string, error)
 }
 
+type ExplainCmdConfig struct {
+	InputCmdConfig
+
+	Breakdown flags.Flag[bool]
+	Tree      flags.Flag[bool]
+	Ground    flags.Flag[bool]
+	Output    flags.Flag[string]
+}
+
+var explainCmdConfig ExplainCmdConfig
+
 func init() {
 	rootCmd.AddCommand(explainCmd)
+	explainCmdConfig = ExplainCmdConfig{
+		InputCmdConfig: newInputCmdConfig(explainCmd),
+
+		Breakdown: flags.BoolP(explainCmd.Flags(), "breakdown", "b", false, "explain every part of the command"),
+		Tree:      flags.Bool(explainCmd.Flags(), "tree", false, "explain every part of the command and show them as a tree"),
+		Ground:    flags.BoolP(explainCmd.Flags(), "ground", "g", false, "ground the explanation in local man pages"),
+		Output:    flags.StringP(explainCmd.Flags(), "output", "o", outputText, "output format (text|json)"),
+	}
 
 	// Here you will define your flags and configuration settings.
 
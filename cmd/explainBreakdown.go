@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/grounding"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+)
+
+const (
+	// maxAlignWidth is the maximum width of the command part column in the aligned output.
+	// Longer parts are printed in their own line.
+	maxAlignWidth = 24
+)
+
+// explanation is the JSON output of the explain command.
+type explanation struct {
+	Command     string              `json:"command"`
+	Explanation string              `json:"explanation,omitempty"`
+	Components  []*shell.Node       `json:"components,omitempty"`
+	Sources     []grounding.Excerpt `json:"sources,omitempty"`
+	// Provider is the name of the provider that answered.
+	Provider string `json:"provider"`
+}
+
+// explainBreakdown parses the command, explains each of its parts and prints them.
+func explainBreakdown(explainer Explainer, command string, output string, excerpts []grounding.Excerpt) error {
+	nodes, err := shell.Parse(command)
+	if err != nil {
+		return errs.New(err, fmt.Sprintf("Cannot break down the command: %v", err))
+	}
+
+	var parts []*shell.Node
+	shell.Walk(nodes, func(node *shell.Node, _ int) {
+		if node.Text != "" {
+			parts = append(parts, node)
+		}
+	})
+
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		// Multiline parts would break the numbered list in the prompt.
+		texts = append(texts, strings.Join(strings.Fields(part.Text), " "))
+	}
+	explanations, err := explainer.ExplainParts(command, texts)
+	if err != nil {
+		return fmt.Errorf("failed to explain command parts: %w", err)
+	}
+	// Providers may return fewer explanations than parts, the remaining parts are not explained.
+	for i := 0; i < len(parts) && i < len(explanations); i++ {
+		parts[i].Explanation = explanations[i]
+	}
+
+	switch {
+	case output == outputJson:
+		return printJson(explanation{Command: command, Components: nodes, Sources: excerpts, Provider: answeredBy(explainer, globalConfig.Provider.Get())})
+	case explainCmdConfig.Tree.Get():
+		fmt.Print(treeToString(nodes, ""))
+	default:
+		fmt.Print(alignedToString(nodes))
+	}
+	return nil
+}
+
+// alignedToString returns the explanations of the leaf components,
+// aligned in two columns, in the order they appear in the command.
+func alignedToString(nodes []*shell.Node) string {
+	var leaves []*shell.Node
+	shell.Walk(nodes, func(node *shell.Node, _ int) {
+		if len(node.Children) == 0 && node.Text != "" {
+			leaves = append(leaves, node)
+		}
+	})
+
+	width := 0
+	for _, leaf := range leaves {
+		if len(leaf.Text) > width && len(leaf.Text) <= maxAlignWidth {
+			width = len(leaf.Text)
+		}
+	}
+
+	out := ""
+	for _, leaf := range leaves {
+		if len(leaf.Text) > width {
+			out += fmt.Sprintf("%s\n%s  %s\n", leaf.Text, strings.Repeat(" ", width), leaf.Explanation)
+		} else {
+			out += fmt.Sprintf("%-*s  %s\n", width, leaf.Text, leaf.Explanation)
+		}
+	}
+	return out
+}
+
+// treeToString returns the explanations of all components as a tree.
+func treeToString(nodes []*shell.Node, indent string) string {
+	out := ""
+	for i, node := range nodes {
+		branch, childIndent := "├── ", indent+"│   "
+		if i == len(nodes)-1 {
+			branch, childIndent = "└── ", indent+"    "
+		}
+
+		out += fmt.Sprintf("%s%s%s", indent, branch, node.Text)
+		if node.Explanation != "" {
+			out += fmt.Sprintf(" — %s", node.Explanation)
+		}
+		out += "\n"
+		out += treeToString(node.Children, childIndent)
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// fallbackProvider is the name of the provider that tries the providers of the fallback chain in order.
+	fallbackProvider = "fallback"
+)
+
+var (
+	// errEmptyFallback is returned when the fallback chain has no providers
+	errEmptyFallback = errors.New("no fallback providers configured")
+)
+
+// retryable is implemented by errors that may not happen on a later request or with another provider,
+// such as rate limits and server errors.
+type retryable interface {
+	Retryable() bool
+}
+
+// isRetryable returns true if the error is retryable, a timeout or a dial error.
+func isRetryable(err error) bool {
+	var r retryable
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// The provider cannot be reached, e.g. the connection is refused or the host is not found,
+	// but another provider may still answer.
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// providersError is returned when every provider of a fallback chain or a race fails.
+type providersError struct {
+	// names are the names of the failed providers, in order.
+	names []string
+	// errs are the provider errors, in the order of names.
+	errs []error
+}
+
+// add appends the error of the provider.
+func (e *providersError) add(name string, err error) {
+	e.names = append(e.names, name)
+	e.errs = append(e.errs, err)
+}
+
+func (e *providersError) Error() string {
+	var failures []string
+	for i, name := range e.names {
+		failures = append(failures, fmt.Sprintf("%s: %v", name, e.errs[i]))
+	}
+	return fmt.Sprintf("all providers failed: %s", strings.Join(failures, "; "))
+}
+
+// fallbackChain is a provider that tries the providers in order and returns the first answer.
+// The next provider is tried only on retryable errors and timeouts, other errors are returned immediately.
+type fallbackChain struct {
+	// links are the providers, in order. The same provider can be listed more than once.
+	links []fallbackLink
+	// answered is the name of the provider that gave the last answer.
+	answered string
+}
+
+// fallbackLink is a provider of a fallback chain.
+type fallbackLink struct {
+	name     string
+	provider Provider
+	// err is the error of a provider that could not be created. Such providers are skipped.
+	err error
+}
+
+// newFallbackProvider creates the fallback chain of the providers configured in fallback.providers.
+// Every provider of the chain is a new instance, even if it is listed more than once.
+func newFallbackProvider(ctx context.Context, opts providerOptions) (Provider, error) {
+	names := globalConfig.FallbackProviders.Get()
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w, set %s", errEmptyFallback, globalConfig.FallbackProviders.Key())
+	}
+	chain := &fallbackChain{}
+	for _, name := range names {
+		factory, ok := providers[name]
+		if !ok || name == fallbackProvider {
+			return nil, fmt.Errorf("invalid fallback provider: %v", name)
+		}
+		provider, err := factory(ctx, opts)
+		if err != nil {
+			log.Warn().Err(err).Str("provider", name).Msg("fallback provider skipped")
+		}
+		chain.links = append(chain.links, fallbackLink{name: name, provider: provider, err: err})
+	}
+	return chain, nil
+}
+
+// try calls the providers in order until one of them answers.
+func (c *fallbackChain) try(call func(provider Provider) error) error {
+	failed := &providersError{}
+	for _, link := range c.links {
+		if link.err != nil {
+			failed.add(link.name, link.err)
+			continue
+		}
+		err := call(link.provider)
+		if err == nil {
+			c.answered = link.name
+			log.Info().Str("provider", link.name).Msg("provider answered")
+			return nil
+		}
+		if !isRetryable(err) {
+			return fmt.Errorf("%s: %w", link.name, err)
+		}
+		log.Warn().Err(err).Str("provider", link.name).Msg("provider failed, trying the next one")
+		failed.add(link.name, err)
+	}
+	return failed
+}
+
+// Answered returns the name of the provider that gave the last answer.
+func (c *fallbackChain) Answered() string {
+	return c.answered
+}
+
+func (c *fallbackChain) Suggest(query string) (suggestion string, err error) {
+	err = c.try(func(provider Provider) (err error) {
+		suggestion, err = provider.Suggest(query)
+		return err
+	})
+	return suggestion, err
+}
+
+func (c *fallbackChain) Explain(command string) (explanation string, err error) {
+	err = c.try(func(provider Provider) (err error) {
+		explanation, err = provider.Explain(command)
+		return err
+	})
+	return explanation, err
+}
+
+func (c *fallbackChain) ExplainParts(command string, parts []string) (explanations []string, err error) {
+	err = c.try(func(provider Provider) (err error) {
+		explanations, err = provider.ExplainParts(command, parts)
+		return err
+	})
+	return explanations, err
+}
+
+func (c *fallbackChain) Translate(command string, from, to shell.Dialect) (translation string, err error) {
+	err = c.try(func(provider Provider) (err error) {
+		translation, err = provider.Translate(command, from, to)
+		return err
+	})
+	return translation, err
+}
+
+func (c *fallbackChain) Script(task string) (script string, err error) {
+	err = c.try(func(provider Provider) (err error) {
+		script, err = provider.Script(task)
+		return err
+	})
+	return script, err
+}
+
+// answeredBy returns the name of the provider that answered, for providers that delegate to others,
+// or the name of the provider itself.
+func answeredBy(provider any, name string) string {
+	if a, ok := provider.(interface{ Answered() string }); ok && a.Answered() != "" {
+		return a.Answered()
+	}
+	return name
+}
+
+// validateFallbackProviders checks that the fallback chain contains only registered providers.
+func validateFallbackProviders(names []string) error {
+	for _, name := range names {
+		if _, ok := providers[name]; !ok || name == fallbackProvider {
+			return fmt.Errorf("unknown provider %q", name)
+		}
+	}
+	return nil
+}
+
+func init() {
+	// The fallback provider creates the other providers, so it is registered here
+	// to avoid an initialization cycle. It runs before the global config is defined in root.go.
+	providers[fallbackProvider] = newFallbackProvider
+}
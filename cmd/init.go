@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// storeKeystore and storeConfig are the storage options of the API key.
+	storeKeystore = "keystore"
+	storeConfig   = "config"
+
+	// testQuery is the query of the connectivity test request.
+	testQuery = "print hello world"
+)
+
+var (
+	// errInitAborted is returned when the user does not save the config
+	errInitAborted = errors.New("init aborted")
+	// errEmptyApiKey is returned when no API key is provided
+	errEmptyApiKey = errors.New("empty api key")
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up aai interactively",
+	Long: `Set up aai interactively.
+It asks for the provider, its API key, the model and your shell,
+verifies the connection with a test request and writes the config file.
+The API key can be stored in the encrypted keystore, in the config file,
+or referenced with cmd:, file: or env:.
+Optionally, the shell integration is installed. It adds the aai-run function,
+which puts the suggested command in the command line, so it can be edited before it is run.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		ctx := cmd.Context()
+		fs := GetFs(ctx)
+		p := newPrompter(cmd)
+
+		file := initCmdConfig.File.Get()
+		if file == "" {
+			if file, _, err = findConfigFile(fs, userConfigDir(), configFileName); err != nil {
+				return fmt.Errorf("failed to find config file: %w", err)
+			}
+		}
+		// values are the config values written to the file, by key.
+		values := make(map[string]any)
+
+		provider, err := p.choose("Provider", providerNames(), globalConfig.Provider.Get())
+		if err != nil {
+			return err
+		}
+		values[globalConfig.Provider.Key()] = provider
+
+		if apiKey, ok := providerApiKey(provider); ok {
+			reference, err := askApiKey(cmd, p, provider)
+			if err != nil {
+				return err
+			}
+			values[apiKey.Key()] = reference
+		}
+
+		if provider == "openai" {
+			model, err := p.ask("Model", globalConfig.Model.Get())
+			if err != nil {
+				return err
+			}
+			values[globalConfig.Model.Key()] = model
+		}
+
+		dialect, err := p.choose("Shell", dialectList(), globalConfig.Shell.Get())
+		if err != nil {
+			return err
+		}
+		values[globalConfig.Shell.Key()] = dialect
+
+		// Apply the answers, so that the test request uses them
+		if err = config.Traverse(&globalConfig, func(value config.AnyValue) error {
+			if v, ok := values[value.Key()]; ok {
+				value.SetAny(v)
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to apply config: %w", err)
+		}
+
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Testing the connection...")
+		if err = testProvider(cmd, provider); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "The test request failed: %v\n", err)
+			save, err := p.confirm("Save the config anyway?", false)
+			if err != nil {
+				return err
+			}
+			if !save {
+				return errs.New(errInitAborted, "The config was not saved")
+			}
+		}
+
+		err = updateConfigFile(fs, file, func(fileConfig *viper.Viper) error {
+			for key, value := range values {
+				fileConfig.Set(key, value)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Config written to %s\n", file)
+
+		return offerShellIntegration(cmd, p, shell.Dialect(dialect))
+	},
+}
+
+// askApiKey asks for the provider API key and stores it, if needed.
+// It returns the config value of the key: a secret reference or the plain text key.
+func askApiKey(cmd *cobra.Command, p *prompter, provider string) (string, error) {
+	key, err := p.secret(fmt.Sprintf("%s API key (or a cmd:, file: or env: reference)", provider))
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return "", errs.New(errEmptyApiKey, "Please provide an API key")
+	}
+	if secrets.IsReference(key) {
+		return key, nil
+	}
+
+	store, err := p.choose("Store the key in", []string{storeKeystore, storeConfig}, storeKeystore)
+	if err != nil {
+		return "", err
+	}
+	if store == storeConfig {
+		return key, nil
+	}
+
+	keystore, err := openKeystore(cmd.Context(), true)
+	if err != nil {
+		return "", fmt.Errorf("failed to open keystore: %w", err)
+	}
+	keystore.Set(provider, key)
+	if err = keystore.Save(); err != nil {
+		return "", fmt.Errorf("failed to save keystore: %w", err)
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Key stored in %s\n", keystore.Path())
+	return secrets.KeystoreScheme + provider, nil
+}
+
+// testProvider sends a test request to the provider.
+func testProvider(cmd *cobra.Command, name string) error {
+	provider, err := newProvider(cmd.Context(), name, providerOptions{})
+	if err != nil {
+		return err
+	}
+	suggestion, err := provider.Suggest(testQuery)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "The connection works, %q suggests: %s\n", testQuery, suggestion)
+	return nil
+}
+
+// offerShellIntegration installs the shell integration if the user agrees.
+func offerShellIntegration(cmd *cobra.Command, p *prompter, dialect shell.Dialect) error {
+	fs := GetFs(cmd.Context())
+	file, err := rcFile(dialect)
+	if err != nil {
+		// There is no integration for the shell
+		return nil
+	}
+	installed, current, err := shellIntegrationInstalled(fs, dialect)
+	if err != nil || current {
+		return err
+	}
+
+	question := fmt.Sprintf("Install the shell integration in %s?", file)
+	if installed {
+		question = fmt.Sprintf("Update the shell integration in %s?", file)
+	}
+	install, err := p.confirm(question, true)
+	if err != nil || !install {
+		return err
+	}
+	if _, err = installShellIntegration(fs, dialect); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Shell integration installed, restart the shell and run: aai-run <query>\n")
+	return nil
+}
+
+type InitCmdConfig struct {
+	File flags.Flag[string]
+}
+
+var initCmdConfig InitCmdConfig
+
+func init() {
+	var err error
+	rootCmd.AddCommand(initCmd)
+
+	initCmdConfig = InitCmdConfig{
+		File: flags.StringP(initCmd.Flags(), "file", "f", "", "file to write config to, $HOME/.aai/config.yaml by default"),
+	}
+	if err = initCmd.MarkFlagFilename("file", configFileTypes...); err != nil {
+		panic(err)
+	}
+}
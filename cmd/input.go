@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// stdinArg is the argument that makes a command read its input from stdin.
+	stdinArg = "-"
+	// maxContextSize is the maximum size of the context attached to a prompt, in bytes.
+	// Longer contexts are truncated from the beginning, since the end of
+	// logs and command outputs is usually the most relevant part.
+	maxContextSize = 8 * 1024
+)
+
+var (
+	// errTooManyArgs is returned when more than one positional argument is provided
+	errTooManyArgs = errors.New("too many arguments provided")
+	// errStdinUsedTwice is returned when stdin is requested both as the input and as the context
+	errStdinUsedTwice = errors.New("stdin cannot be used as both the input and the context")
+)
+
+// input is the text provided to a command, with an optional context.
+type input struct {
+	// Text is the query or the command provided by the user.
+	Text string
+	// Context is additional data, such as a command output or an error log,
+	// that is attached to the prompt.
+	Context string
+}
+
+// InputCmdConfig holds the flags shared by commands that accept input from stdin.
+type InputCmdConfig struct {
+	ContextFile flags.Flag[string]
+}
+
+// newInputCmdConfig defines input flags for the provided command.
+func newInputCmdConfig(cmd *cobra.Command) InputCmdConfig {
+	cfg := InputCmdConfig{
+		ContextFile: flags.StringP(cmd.Flags(), "context-file", "c", "", `file attached to the prompt as context, "-" reads stdin`),
+	}
+	if err := cmd.MarkFlagFilename("context-file"); err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// maxOneArg validates that at most one positional argument is provided.
+func maxOneArg(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return errs.New(errTooManyArgs, "Please provide a single argument, use quotes if it contains spaces")
+	}
+	return nil
+}
+
+// readInput returns the command input. The text is the positional argument or,
+// if it is missing or equal to "-", the data piped to stdin. The context is read
+// from the --context-file flag, stdin is read as the context only with "--context-file -",
+// so that commands run in scripts do not consume the input of the script.
+// missingErr is returned if there is neither an argument nor piped stdin.
+func readInput(cmd *cobra.Command, args []string, cfg InputCmdConfig, missingErr error) (input, error) {
+	var in input
+	var err error
+
+	stdin := cmd.InOrStdin()
+	piped := isPiped(stdin)
+	stdinUsed := false
+
+	switch {
+	case len(args) == 1 && args[0] != stdinArg:
+		in.Text = args[0]
+	case piped || (len(args) == 1 && args[0] == stdinArg):
+		if in.Text, err = readAll(stdin); err != nil {
+			return input{}, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		stdinUsed = true
+	default:
+		return input{}, missingErr
+	}
+	if in.Text == "" {
+		return input{}, missingErr
+	}
+
+	contextFile := cfg.ContextFile.Get()
+	switch {
+	case contextFile == stdinArg:
+		if stdinUsed {
+			return input{}, errs.New(errStdinUsedTwice, "Stdin is already used as the input, please provide the context with a file")
+		}
+		in.Context, err = readAll(stdin)
+	case contextFile != "":
+		var data []byte
+		data, err = afero.ReadFile(GetFs(cmd.Context()), contextFile)
+		in.Context = strings.TrimSpace(string(data))
+	}
+	if err != nil {
+		return input{}, fmt.Errorf("failed to read context: %w", err)
+	}
+
+	if len(in.Context) > maxContextSize {
+		log.Warn().Int("size", len(in.Context)).Msgf("Context is too long, using the last %d bytes", maxContextSize)
+		// The cut is moved forward to the start of a rune, so that multi-byte characters are not split.
+		cut := len(in.Context) - maxContextSize
+		for cut < len(in.Context) && !utf8.RuneStart(in.Context[cut]) {
+			cut++
+		}
+		in.Context = in.Context[cut:]
+	}
+	return in, nil
+}
+
+// isPiped returns true if the reader is a file that is not a terminal,
+// for example a pipe or a redirected file.
+func isPiped(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	if !ok {
+		// Readers set with cmd.SetIn are treated as piped data.
+		return r != nil
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// readAll reads the reader and trims surrounding whitespace.
+func readAll(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
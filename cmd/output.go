@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	// outputText is the human readable output format.
+	outputText = "text"
+	// outputJson is the JSON output format.
+	outputJson = "json"
+	// outputYaml is the YAML output format.
+	outputYaml = "yaml"
+	// outputToml is the TOML output format.
+	outputToml = "toml"
+)
+
+var (
+	// errUnknownOutput is returned when an unsupported output format is requested
+	errUnknownOutput = errors.New("unknown output format")
+)
+
+// printJson prints the value as indented JSON.
+func printJson(value any) error {
+	out, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// printToml prints the value as TOML.
+func printToml(value any) error {
+	out, err := toml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+	fmt.Print(string(out))
+	return nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	// errInvalidAnswer is returned when the answer is not one of the options and there are no more answers
+	errInvalidAnswer = errors.New("invalid answer")
+)
+
+// prompter asks the user questions in interactive commands.
+// Questions are written to stderr, so that they do not mix with the command output.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// eof is true when there are no more answers.
+	eof bool
+}
+
+// newPrompter creates a prompter that reads the answers from the command stdin.
+func newPrompter(cmd *cobra.Command) *prompter {
+	return &prompter{
+		in:  bufio.NewReader(cmd.InOrStdin()),
+		out: cmd.ErrOrStderr(),
+	}
+}
+
+// ask asks the question and returns the answer, or the default value if the answer is empty.
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		_, _ = fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		_, _ = fmt.Fprintf(p.out, "%s: ", question)
+	}
+	answer, err := p.in.ReadString('\n')
+	if err == io.EOF {
+		p.eof = true
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// choose asks the question until the answer is one of the options.
+func (p *prompter) choose(question string, options []string, def string) (string, error) {
+	for {
+		answer, err := p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, ", ")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, option := range options {
+			if answer == option {
+				return answer, nil
+			}
+		}
+		if p.eof {
+			return "", fmt.Errorf("%w: %q", errInvalidAnswer, answer)
+		}
+		_, _ = fmt.Fprintf(p.out, "Please choose one of: %s\n", strings.Join(options, ", "))
+	}
+}
+
+// confirm asks a yes or no question.
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	options := "y/N"
+	if def {
+		options = "Y/n"
+	}
+	answer, err := p.ask(fmt.Sprintf("%s [%s]", question, options), "")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// secret asks for a secret without echo if stdin is a terminal.
+func (p *prompter) secret(question string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return readPassword(question + ": ")
+	}
+	return p.ask(question, "")
+}
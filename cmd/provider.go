@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/examples"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/grounding"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/mock"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/openai"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/redact"
+)
+
+const (
+	// mockProvider is the name of the provider that answers from a rules file, for demos and tests.
+	mockProvider = "mock"
+)
+
+// Provider is an AI provider that implements all aai features.
+type Provider interface {
+	Suggester
+	Explainer
+	Translator
+	ScriptWriter
+}
+
+// providerOptions are the provider independent prompt options.
+type providerOptions struct {
+	// examples are the few-shot examples used in suggestion prompts.
+	examples []examples.Example
+	// context is additional data attached to prompts, such as a command output.
+	context string
+	// grounding are documentation excerpts used in explanation prompts.
+	grounding []grounding.Excerpt
+	// redaction redacts secrets and personal data from prompts.
+	redaction *redact.Pipeline
+	// model overrides the configured model of the provider, if it is set.
+	model string
+}
+
+// providerFactory creates a provider configured with the global config.
+type providerFactory func(ctx context.Context, opts providerOptions) (Provider, error)
+
+// providers is the registry of available providers by name.
+var providers = map[string]providerFactory{
+	"openai":     newOpenAiProvider,
+	mockProvider: newMockProvider,
+}
+
+// providerApiKey returns the API key config value of the provider,
+// or false if the provider does not need a key.
+func providerApiKey(name string) (config.Value[string], bool) {
+	switch name {
+	case "openai":
+		return globalConfig.ApiKey, true
+	}
+	return nil, false
+}
+
+// providerEndpoint returns the base URL of the provider API,
+// or false if the provider does not use the network.
+func providerEndpoint(name string) (string, bool) {
+	switch name {
+	case "openai":
+		return globalConfig.BaseUrl.Get(), true
+	}
+	return "", false
+}
+
+// providerNames returns the sorted names of the registered providers.
+func providerNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateProvider checks that the provider is registered. The registry is read when the value
+// is validated, since providers can be registered after the global config is defined.
+func validateProvider(name string) error {
+	if _, ok := providers[name]; !ok {
+		return fmt.Errorf("%w, must be one of %v", config.ErrNotAllowed, providerNames())
+	}
+	return nil
+}
+
+// newProvider creates the provider registered with the given name.
+func newProvider(ctx context.Context, name string, opts providerOptions) (Provider, error) {
+	var err error
+	factory, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %v", name)
+	}
+	if opts.redaction, err = newRedaction(ctx); err != nil {
+		return nil, err
+	}
+	return factory(ctx, opts)
+}
+
+// newRedaction creates the redaction pipeline with the built-in detectors and the user rules.
+func newRedaction(ctx context.Context) (*redact.Pipeline, error) {
+	rules, err := redact.ParseRules(globalConfig.RedactRules.Get())
+	if err != nil {
+		return nil, errs.New(err, fmt.Sprintf("Invalid %s in config: %v", globalConfig.RedactRules.Key(), err))
+	}
+	return redact.NewPipeline(append(redact.Detectors, rules...)...), nil
+}
+
+func newOpenAiProvider(ctx context.Context, opts providerOptions) (Provider, error) {
+	var err error
+	var openaiCfg openai.Config
+	if err = config.Decode(globalConfig, &openaiCfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	if openaiCfg.ApiKey, err = newSecretResolver(ctx).Resolve(ctx, openaiCfg.ApiKey); err != nil {
+		return nil, fmt.Errorf("failed to resolve openai api key: %w", err)
+	}
+	redactSecrets(openaiCfg.ApiKey)
+	if opts.model != "" {
+		openaiCfg.Model = opts.model
+	}
+	return openai.NewClient(openaiCfg,
+		openai.WithRequestContext(ctx),
+		openai.WithTransport(GetTransport(ctx)),
+		openai.WithExamples(opts.examples),
+		openai.WithContext(opts.context),
+		openai.WithGrounding(opts.grounding),
+		openai.WithRedaction(opts.redaction),
+	), nil
+}
+
+func newMockProvider(ctx context.Context, opts providerOptions) (Provider, error) {
+	var mockCfg mock.Config
+	if err := config.Decode(globalConfig, &mockCfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	rules, err := mock.Load(GetFs(ctx), mockCfg.Rules)
+	if err != nil {
+		return nil, errs.New(err, fmt.Sprintf("Cannot load the mock rules: %v, set %s", err, globalConfig.MockRules.Key()))
+	}
+	// The latency of the rules file is used, unless the latency is set with the flag, env, profile or config file.
+	latency := rules.Latency
+	if globalConfig.MockLatency.(config.AnyValue).Source(globalConfig.Profile.Get()) != config.SourceDefault {
+		latency = mockCfg.Latency
+	}
+	return mock.New(ctx, rules, latency), nil
+}
@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"net/url"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/examples"
 	"github.com/TomaszDomagala/ask-ai-cli/pkg/openai"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/redact"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -20,18 +24,8 @@ import (
 )
 
 var (
-	// defaultConfigPaths is a list of paths to check for config files
-	defaultConfigPaths = []string{
-		"/etc/aai/",
-		"$HOME/.aai/",
-		".",
-	}
 	// configFileName is the name of the config file, without extension
 	configFileName = "config"
-	// configFileType is the type of the config file
-	configFileType = "yaml"
-	// firstDefaultConfigFile is the path to the first default config file
-	firstDefaultConfigFile = filepath.Join(defaultConfigPaths[0], configFileName+"."+configFileType)
 )
 
 var (
@@ -55,32 +49,34 @@ what the command does before running it.
 Example:
     $ aai "show files with size greater than 1MB"
 	find . -size +1M
+
+The query can also be piped to stdin. To attach piped data to the prompt
+as context, provide the query as an argument and use --context-file -:
+    $ ls -la | aai -c - "remove the largest file"
+
+Use --verify to check the suggested flags against the local man pages,
+and --fix to ask again when unknown flags are found. With --ground-help,
+programs without a man page are run with --help.
+
+Every config value can be set with an AAI_ environment variable, for example
+AAI_PROVIDERS_OPENAI_MODEL for providers.openai.model. The OpenAI API key can
+also be set with OPENAI_API_KEY. Values are resolved in order: flag,
+environment variable, profile, config file, default.
+The openai.* keys and their AAI_OPENAI_* variables are deprecated
+aliases of providers.openai.*.
 `,
 
-	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) != 1 {
-			return errs.New(errNoQueryArg, "Please provide a query argument")
-		}
-		return nil
-	},
+	Args: maxOneArg,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		var err error
 		cfg := GetGlobalConfig(cmd.Context())
 
-		cfg.SetConfigName(configFileName)
-		cfg.SetConfigType(configFileType)
-		for _, path := range defaultConfigPaths {
-			cfg.AddConfigPath(path)
-		}
-
-		err = cfg.ReadInConfig()
-		if err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				return fmt.Errorf("failed to read config: %w", err)
-			}
-
-			// We cannot log here, because the logger is not configured yet
-			// we will log it later
+		// Merge the system, user and project config files.
+		// We cannot log here, because the logger is not configured yet
+		// we will log it later
+		// The doctor command reports the errors itself
+		if err = readConfigFiles(GetFs(cmd.Context()), cfg); err != nil && cmd != doctorCmd {
+			return fmt.Errorf("failed to read config: %w", err)
 		}
 
 		// Setup config by attaching viper to the config struct
@@ -90,6 +86,30 @@ Example:
 			return fmt.Errorf("failed to attach config: %w", err)
 		}
 
+		// Apply the selected profile over the config file values
+		profile := globalConfig.Profile.Get()
+		if profile != "" {
+			if err = config.ApplyProfile(cfg, profile); err != nil && cmd != doctorCmd {
+				return errs.New(err, fmt.Sprintf("Profile %q is not defined, available profiles: %v", profile, config.Profiles(cfg)))
+			}
+		}
+
+		// Mask secrets in logs, after the profile has been applied
+		if err = redactConfigSecrets(&globalConfig); err != nil {
+			return fmt.Errorf("failed to redact secrets: %w", err)
+		}
+
+		// Config commands must work with an invalid config, so that it can be fixed
+		if !isConfigCommand(cmd) && cmd != initCmd && cmd != doctorCmd {
+			invalid, err := config.Validate(&globalConfig)
+			if err != nil {
+				return fmt.Errorf("failed to validate config: %w", err)
+			}
+			if len(invalid) > 0 {
+				return errs.New(invalid[0], fmt.Sprintf("Invalid config: %v, run \"aai config validate\" for details", invalid[0]))
+			}
+		}
+
 		// Setup logs
 		loglevel, err := zerolog.ParseLevel(globalConfig.LogLevel.Get())
 		if err != nil {
@@ -98,34 +118,80 @@ Example:
 		// From now on, we can use loggers
 		zerolog.SetGlobalLevel(loglevel)
 
-		if cfg.ConfigFileUsed() != "" {
-			log.Info().Msgf("Using config file: %s", cfg.ConfigFileUsed())
+		if len(configFiles) > 0 {
+			for _, file := range configFiles {
+				log.Info().Msgf("Using config file: %s", file.path)
+				if file.outdated {
+					log.Warn().Msgf("Config file %s uses config version %d, run \"aai config migrate\" to upgrade it", file.path, file.version)
+				}
+				for _, key := range file.ignored {
+					log.Warn().Msgf("Ignoring %s in project config file %s, set it in the user config file instead", key, file.path)
+				}
+			}
 		} else {
 			log.Warn().Msgf("No config file found, using defaults")
 		}
+		for env, deprecated := range deprecatedEnvs {
+			if os.Getenv(deprecated) != "" {
+				log.Warn().Msgf("Environment variable %s is deprecated, use %s", deprecated, env)
+			}
+		}
+		if profile != "" {
+			log.Info().Msgf("Using profile: %s", profile)
+		}
 
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var err error
-		var suggester Suggester
 
-		switch globalConfig.Provider.Get() {
-		case "openai":
-			var openaiCfg openai.Config
-			err := config.Decode(globalConfig, &openaiCfg)
-			if err != nil {
-				return fmt.Errorf("failed to decode config: %w", err)
-			}
-			suggester = openai.NewClient(openaiCfg)
-		default:
-			return fmt.Errorf("unknown provider: %v", globalConfig.Provider.Get())
+		output := rootCmdConfig.Output.Get()
+		if output != outputText && output != outputJson {
+			return errs.New(errUnknownOutput, fmt.Sprintf("Unknown output format %q, use %q or %q", output, outputText, outputJson))
+		}
+
+		in, err := readInput(cmd, args, rootCmdConfig.InputCmdConfig, errs.New(errNoQueryArg, "Please provide a query argument"))
+		if err != nil {
+			return err
 		}
+		query := in.Text
 
-		query := args[0]
-		response, err := suggester.Suggest(query)
+		opts, err := suggestOptions(cmd, in)
 		if err != nil {
-			return fmt.Errorf("failed to suggest a command: %w", err)
+			return err
+		}
+
+		var suggester Suggester
+		var response string
+		name := globalConfig.Provider.Get()
+		if rootCmdConfig.Race.Get() {
+			winner, err := raceTargets(cmd.Context(), query, opts)
+			if err != nil {
+				return fmt.Errorf("failed to suggest a command: %w", err)
+			}
+			log.Info().Str("target", winner.Target).Int64("latency_ms", winner.LatencyMs).Msg("target answered first")
+			response, name = winner.Command, winner.Target
+			// The race requests are canceled, the winner is created again for the verification
+			opts.model = winner.target.model
+			if suggester, err = newProvider(cmd.Context(), winner.target.provider, opts); err != nil {
+				return err
+			}
+		} else {
+			if suggester, err = newProvider(cmd.Context(), name, opts); err != nil {
+				return err
+			}
+			if response, err = suggester.Suggest(query); err != nil {
+				return fmt.Errorf("failed to suggest a command: %w", err)
+			}
+		}
+		if rootCmdConfig.Verify.Get() || rootCmdConfig.Fix.Get() {
+			response, err = verifySuggestion(cmd.Context(), cmd.ErrOrStderr(), suggester, query, response, rootCmdConfig.Fix.Get())
+			if err != nil {
+				return err
+			}
+		}
+		if output == outputJson {
+			return printJson(suggestion{Query: query, Command: response, Provider: answeredBy(suggester, name)})
 		}
 		fmt.Println(response)
 
@@ -133,6 +199,50 @@ Example:
 	},
 }
 
+// suggestOptions returns the provider options of suggestion prompts:
+// the examples most relevant to the query and the input context.
+func suggestOptions(cmd *cobra.Command, in input) (providerOptions, error) {
+	library, err := examples.Load(GetFs(cmd.Context()), globalConfig.ExamplesFile.Get())
+	if err != nil {
+		return providerOptions{}, fmt.Errorf("failed to load examples: %w", err)
+	}
+	relevant := library.Select(in.Text, globalConfig.ExamplesCount.Get())
+	log.Debug().Int("count", len(relevant)).Msg("selected examples")
+	return providerOptions{examples: relevant, context: in.Context}, nil
+}
+
+// suggestion is the JSON output of a suggested command.
+type suggestion struct {
+	Query   string `json:"query"`
+	Command string `json:"command"`
+	// Provider is the name of the provider that answered.
+	Provider string `json:"provider"`
+}
+
+// validateRedactRules checks that the redaction rules are valid regular expressions.
+func validateRedactRules(rules map[string]string) error {
+	_, err := redact.ParseRules(rules)
+	return err
+}
+
+// validateUrl checks that the value is an absolute http or https URL.
+func validateUrl(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("expected an http or https url")
+	}
+	return nil
+}
+
+// validateLogLevel checks that the level is a zerolog level.
+func validateLogLevel(level string) error {
+	_, err := zerolog.ParseLevel(level)
+	return err
+}
+
 type Suggester interface {
 	// Suggest returns a suggestion for a given query.
 	Suggest(query string) (string, error)
@@ -142,7 +252,7 @@ type Suggester interface {
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: logRedactor})
 
 	global := viper.New()
 	fs := afero.NewOsFs()
@@ -167,13 +277,75 @@ func Execute() {
 type GlobalConfig struct {
 	Provider config.Value[string]
 	LogLevel config.Value[string]
+	// Profile is the name of the profile whose values override the config file values.
+	Profile config.Value[string]
+	// Shell is the user's shell, used by the shell integration.
+	Shell config.Value[string]
 
 	OpenAiConfig
+	MockConfig
+	FallbackConfig
+	CompareConfig
+	ExamplesConfig
+	ScriptConfig
+	GroundingConfig
+	SecretsConfig
+	RedactConfig
+}
+
+type MockConfig struct {
+	// MockRules is the path of the rules file of the mock provider.
+	MockRules config.Value[string]
+	// MockLatency is the default latency of the mock provider answers.
+	MockLatency config.Value[time.Duration]
+}
+
+type FallbackConfig struct {
+	// FallbackProviders are the providers tried in order by the fallback provider.
+	FallbackProviders config.Value[[]string]
+}
+
+type CompareConfig struct {
+	// CompareTargets are the providers, with optional models, compared by the compare command and --race.
+	CompareTargets config.Value[[]string]
+	// CompareConcurrency is the maximum number of concurrent requests.
+	CompareConcurrency config.Value[int]
+	// CompareTimeout is the deadline shared by all compared requests.
+	CompareTimeout config.Value[time.Duration]
+}
+
+type RedactConfig struct {
+	// RedactRules are the user redaction rules, a map of names to regular expressions.
+	RedactRules config.Value[map[string]string]
+}
+
+type ExamplesConfig struct {
+	// ExamplesFile is the path of the few-shot examples library.
+	ExamplesFile config.Value[string]
+	// ExamplesCount is the maximum number of examples included in a prompt.
+	ExamplesCount config.Value[int]
+}
+
+type GroundingConfig struct {
+	// GroundHelp runs programs without a man page with --help, to ground and verify their flags.
+	GroundHelp config.Value[bool]
+}
+
+type SecretsConfig struct {
+	// Keystore is the path of the encrypted keystore file.
+	Keystore config.Value[string]
+}
+
+type ScriptConfig struct {
+	// ScriptMaxTokens is the max tokens of script generation, separate from quick suggestions.
+	ScriptMaxTokens config.Value[int]
 }
 
 type OpenAiConfig struct {
 	// ApiKey for OpenAI API
 	ApiKey config.Value[string]
+	// BaseUrl of the OpenAI API, e.g. a local OpenAI compatible server.
+	BaseUrl config.Value[string]
 
 	// OpenAi request settings.
 	// Description: https://beta.openai.com/docs/api-reference/completions/create
@@ -184,24 +356,91 @@ type OpenAiConfig struct {
 	TopP             config.Value[float64]
 	FrequencyPenalty config.Value[float64]
 	PresencePenalty  config.Value[float64]
+	// Timeout of a single request.
+	Timeout config.Value[time.Duration]
 }
 
 var globalConfig GlobalConfig
 
+type RootCmdConfig struct {
+	InputCmdConfig
+
+	Verify flags.Flag[bool]
+	Fix    flags.Flag[bool]
+	Output flags.Flag[string]
+	// Race sends the query to all compare targets and uses the first answer.
+	Race flags.Flag[bool]
+	// ShowSecrets disables masking of secrets in config views and logs.
+	ShowSecrets flags.Flag[bool]
+}
+
+var rootCmdConfig RootCmdConfig
+
 func init() {
+	rootCmdConfig = RootCmdConfig{
+		InputCmdConfig: newInputCmdConfig(rootCmd),
+
+		Verify: flags.Bool(rootCmd.Flags(), "verify", false, "check the suggested flags against local man pages"),
+		Fix:    flags.Bool(rootCmd.Flags(), "fix", false, "like --verify, but ask again if unknown flags are found"),
+		Output: flags.StringP(rootCmd.Flags(), "output", "o", outputText, "output format (text|json)"),
+		Race:   flags.Bool(rootCmd.Flags(), "race", false, "send the query to all compare targets and use the first answer"),
+
+		ShowSecrets: flags.Bool(rootCmd.PersistentFlags(), "show-secrets", false, "do not mask secrets in config views and logs"),
+	}
+
 	// define global config
 	globalConfig = GlobalConfig{
-		Provider: config.String("provider", config.WithFlag(rootCmd.PersistentFlags(), "provider", "openai", "provider to use for suggestions")),
-		LogLevel: config.String("loglevel", config.WithFlag(rootCmd.PersistentFlags(), "loglevel", "disabled", "log level (zerolog)")),
+		Provider: config.String("provider", config.WithFlag(rootCmd.PersistentFlags(), "provider", "openai", "provider to use for suggestions"), config.WithValidate(validateProvider)),
+		LogLevel: config.String("loglevel", config.WithFlag(rootCmd.PersistentFlags(), "loglevel", "disabled", "log level (zerolog)"), config.WithValidate(validateLogLevel)),
+		Profile:  config.String("profile", config.WithFlag(rootCmd.PersistentFlags(), "profile", "", "config profile to use")),
+		Shell:    config.String("shell", config.WithFlag(rootCmd.PersistentFlags(), "shell", string(detectShell()), "user's shell"), config.WithEnum(dialectList()...)),
 
 		OpenAiConfig: OpenAiConfig{
-			ApiKey:           config.String("openai.apikey", config.WithFlag(rootCmd.PersistentFlags(), "openai-apikey", "", "openai api key")),
-			Model:            config.String("openai.model", config.WithFlag(rootCmd.PersistentFlags(), "openai-model", "text-davinci-002", "openai model to use for completion")),
-			Temperature:      config.Float64("openai.temperature", config.WithFlag(rootCmd.PersistentFlags(), "openai-temperature", 0.2, "temperature")),
-			MaxTokens:        config.Int("openai.maxtokens", config.WithFlag(rootCmd.PersistentFlags(), "openai-maxtokens", 100, "max tokens")),
-			TopP:             config.Float64("openai.topp", config.WithFlag(rootCmd.PersistentFlags(), "openai-topp", 1.0, "top p")),
-			FrequencyPenalty: config.Float64("openai.frequencypenalty", config.WithFlag(rootCmd.PersistentFlags(), "openai-frequencypenalty", 0.0, "frequency penalty")),
-			PresencePenalty:  config.Float64("openai.presencepenalty", config.WithFlag(rootCmd.PersistentFlags(), "openai-presencepenalty", 0.0, "presence penalty")),
+			ApiKey:           config.String("providers.openai.apikey", config.WithFlag(rootCmd.PersistentFlags(), "openai-apikey", "", "openai api key or secret reference (cmd:, file:, env:, keystore:)"), deprecatedEnv[string]("providers.openai.apikey"), config.WithEnv[string]("OPENAI_API_KEY"), config.WithSecret[string]()),
+			BaseUrl:          config.String("providers.openai.baseurl", config.WithFlag(rootCmd.PersistentFlags(), "openai-baseurl", openai.DefaultBaseUrl, "openai api base url"), config.WithValidate(validateUrl), deprecatedEnv[string]("providers.openai.baseurl")),
+			Model:            config.String("providers.openai.model", config.WithFlag(rootCmd.PersistentFlags(), "openai-model", "text-davinci-002", "openai model to use for completion"), deprecatedEnv[string]("providers.openai.model")),
+			Temperature:      config.Float64("providers.openai.temperature", config.WithFlag(rootCmd.PersistentFlags(), "openai-temperature", 0.2, "temperature"), config.WithRange(0.0, 2.0), deprecatedEnv[float64]("providers.openai.temperature")),
+			MaxTokens:        config.Int("providers.openai.maxtokens", config.WithFlag(rootCmd.PersistentFlags(), "openai-maxtokens", 100, "max tokens"), config.WithRange(1, 4096), deprecatedEnv[int]("providers.openai.maxtokens")),
+			TopP:             config.Float64("providers.openai.topp", config.WithFlag(rootCmd.PersistentFlags(), "openai-topp", 1.0, "top p"), config.WithRange(0.0, 1.0), deprecatedEnv[float64]("providers.openai.topp")),
+			FrequencyPenalty: config.Float64("providers.openai.frequencypenalty", config.WithFlag(rootCmd.PersistentFlags(), "openai-frequencypenalty", 0.0, "frequency penalty"), config.WithRange(-2.0, 2.0), deprecatedEnv[float64]("providers.openai.frequencypenalty")),
+			PresencePenalty:  config.Float64("providers.openai.presencepenalty", config.WithFlag(rootCmd.PersistentFlags(), "openai-presencepenalty", 0.0, "presence penalty"), config.WithRange(-2.0, 2.0), deprecatedEnv[float64]("providers.openai.presencepenalty")),
+			Timeout:          config.Duration("providers.openai.timeout", config.WithFlag(rootCmd.PersistentFlags(), "openai-timeout", time.Minute, "request timeout"), config.WithRange(time.Second, 10*time.Minute), deprecatedEnv[time.Duration]("providers.openai.timeout")),
+		},
+
+		MockConfig: MockConfig{
+			MockRules:   config.String("providers.mock.rules", config.WithFlag(rootCmd.PersistentFlags(), "mock-rules", "$HOME/.aai/mock.yaml", "rules file of the mock provider")),
+			MockLatency: config.Duration("providers.mock.latency", config.WithFlag(rootCmd.PersistentFlags(), "mock-latency", time.Duration(0), "latency of the mock provider answers"), config.WithRange(time.Duration(0), time.Minute)),
+		},
+
+		FallbackConfig: FallbackConfig{
+			FallbackProviders: config.StringSlice("fallback.providers", config.WithFlag(rootCmd.PersistentFlags(), "fallback-providers", []string{"openai"}, "providers tried in order by the fallback provider"), config.WithValidate(validateFallbackProviders)),
+		},
+
+		CompareConfig: CompareConfig{
+			CompareTargets:     config.StringSlice("compare.targets", config.WithFlag(rootCmd.PersistentFlags(), "targets", []string{}, "providers compared by compare and --race, with optional models, e.g. openai:gpt-3.5-turbo-instruct"), config.WithValidate(validateTargets)),
+			CompareConcurrency: config.Int("compare.concurrency", config.WithFlag(rootCmd.PersistentFlags(), "concurrency", 4, "max number of concurrent requests of compare and --race"), config.WithRange(1, 32)),
+			CompareTimeout:     config.Duration("compare.timeout", config.WithFlag(rootCmd.PersistentFlags(), "compare-timeout", time.Minute, "deadline of all requests of compare and --race"), config.WithRange(time.Second, 10*time.Minute)),
+		},
+
+		ExamplesConfig: ExamplesConfig{
+			ExamplesFile:  config.String("examples.file", config.WithFlag(rootCmd.PersistentFlags(), "examples-file", "$HOME/.aai/examples.yaml", "file with few-shot examples")),
+			ExamplesCount: config.Int("examples.count", config.WithFlag(rootCmd.PersistentFlags(), "examples-count", 3, "max number of examples used in a prompt"), config.WithRange(0, 20)),
+		},
+
+		ScriptConfig: ScriptConfig{
+			ScriptMaxTokens: config.Int("script.maxtokens", config.WithFlag(rootCmd.PersistentFlags(), "script-maxtokens", 1000, "max tokens of generated scripts"), config.WithRange(1, 4096)),
+		},
+
+		GroundingConfig: GroundingConfig{
+			GroundHelp: config.Bool("grounding.help", config.WithFlag(rootCmd.PersistentFlags(), "ground-help", false, "run programs without a man page with --help when grounding explanations and verifying flags")),
+		},
+
+		SecretsConfig: SecretsConfig{
+			Keystore: config.String("secrets.keystore", config.WithFlag(rootCmd.PersistentFlags(), "keystore", "$HOME/.aai/keystore.json", "encrypted keystore file")),
+		},
+
+		RedactConfig: RedactConfig{
+			RedactRules: config.StringMap("redact.rules", config.WithFlag(rootCmd.PersistentFlags(), "redact-rules", map[string]string{}, "additional redaction rules, name=regexp pairs"), config.WithValidate(validateRedactRules)),
 		},
 	}
 
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errNoTaskArg is returned when no task argument is provided
+	errNoTaskArg = errors.New("no task argument provided")
+	// errDangerousScript is returned when a script with dangerous operations is written without --force
+	errDangerousScript = errors.New("script contains dangerous operations")
+)
+
+// scriptCmd represents the script command
+var scriptCmd = &cobra.Command{
+	Use:   "script <task>",
+	Short: "Generate a bash script for a task",
+	Long: `This command will generate a complete bash script for the provided task,
+with strict mode, argument parsing and comments.
+
+The script is checked for dangerous operations, such as removing the root directory
+or executing code downloaded from the internet. Scripts with dangerous operations,
+and scripts that cannot be parsed, are not written to a file unless --force is used.
+
+Example:
+	$ aai script --file backup.sh "back up a directory to a tar.gz archive with a date in its name"
+`,
+	Args: maxOneArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in, err := readInput(cmd, args, scriptCmdConfig.InputCmdConfig, errs.New(errNoTaskArg, "Please provide a task for the script"))
+		if err != nil {
+			return err
+		}
+
+		writer, err := newProvider(cmd.Context(), globalConfig.Provider.Get(), providerOptions{
+			context: in.Context,
+		})
+		if err != nil {
+			return err
+		}
+
+		script, err := writer.Script(in.Text)
+		if err != nil {
+			return fmt.Errorf("failed to generate a script: %w", err)
+		}
+
+		stderr := cmd.ErrOrStderr()
+		findings, err := shell.Analyze(script)
+		// A script that cannot be parsed cannot be checked, so it is treated as dangerous.
+		dangerous := err != nil
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "warning: the script is not valid bash: %v\n", err)
+		}
+		for _, finding := range findings {
+			dangerous = dangerous || finding.Severity == shell.Danger
+			_, _ = fmt.Fprintf(stderr, "%s\n", finding)
+		}
+
+		file := scriptCmdConfig.File.Get()
+		if file == "" {
+			fmt.Println(script)
+			return nil
+		}
+		if dangerous && !scriptCmdConfig.Force.Get() {
+			fmt.Println(script)
+			return errs.New(errDangerousScript, "The script contains dangerous operations or is not valid bash and was not written, review it and use --force to write it anyway")
+		}
+
+		fs := GetFs(cmd.Context())
+		if err = fs.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(file), err)
+		}
+		if err = afero.WriteFile(fs, file, []byte(script+"\n"), 0755); err != nil {
+			return fmt.Errorf("failed to write script: %w", err)
+		}
+		// WriteFile does not change permissions of existing files.
+		if err = fs.Chmod(file, 0755); err != nil {
+			return fmt.Errorf("failed to make script executable: %w", err)
+		}
+		_, _ = fmt.Fprintf(stderr, "Script written to %s\n", file)
+		return nil
+	},
+}
+
+type ScriptWriter interface {
+	// Script returns a bash script for a given task.
+	Script(task string) (string, error)
+}
+
+type ScriptCmdConfig struct {
+	InputCmdConfig
+
+	File  flags.Flag[string]
+	Force flags.Flag[bool]
+}
+
+var scriptCmdConfig ScriptCmdConfig
+
+func init() {
+	rootCmd.AddCommand(scriptCmd)
+	scriptCmdConfig = ScriptCmdConfig{
+		InputCmdConfig: newInputCmdConfig(scriptCmd),
+
+		File:  flags.StringP(scriptCmd.Flags(), "file", "f", "", "executable file to write the script to"),
+		Force: flags.Bool(scriptCmd.Flags(), "force", false, "write the script even if it contains dangerous operations"),
+	}
+	if err := scriptCmd.MarkFlagFilename("file", "sh"); err != nil {
+		panic(err)
+	}
+}
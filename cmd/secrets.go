@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+
+	"github.com/spf13/afero"
+	"golang.org/x/term"
+)
+
+const (
+	// keystorePassphraseEnv is the environment variable with the keystore passphrase.
+	// If it is not set, the passphrase is read from the terminal.
+	keystorePassphraseEnv = "AAI_KEYSTORE_PASSPHRASE"
+)
+
+// logRedactor masks secrets in logs, it is the output of the global logger.
+var logRedactor = secrets.NewRedactor(os.Stderr)
+
+var (
+	// errNoTerminal is returned when a password is needed, but stdin is not a terminal
+	errNoTerminal = errors.New("cannot read password, stdin is not a terminal")
+
+	// errPassphraseMismatch is returned when the repeated passphrase of a new keystore is different
+	errPassphraseMismatch = errors.New("passphrases do not match")
+)
+
+// redactSecrets registers secrets that are masked in logs, unless --show-secrets is set.
+func redactSecrets(values ...string) {
+	if rootCmdConfig.ShowSecrets.Get() {
+		return
+	}
+	logRedactor.Add(values...)
+}
+
+// redactConfigSecrets registers the plain text values of the secret config values.
+// Secret references are resolved later, see newSecretResolver.
+func redactConfigSecrets(cfg any) error {
+	return config.Traverse(cfg, func(value config.AnyValue) error {
+		if secret, ok := value.GetAny().(string); ok && value.IsSecret() && !secrets.IsReference(secret) {
+			redactSecrets(secret)
+		}
+		return nil
+	})
+}
+
+// newSecretResolver creates a resolver of secret references in config values.
+func newSecretResolver(ctx context.Context) *secrets.Resolver {
+	return secrets.NewResolver(GetFs(ctx), func() (*secrets.Keystore, error) {
+		return openKeystore(ctx, false)
+	})
+}
+
+var (
+	// openedKeystore is the keystore opened by openKeystore, so that the passphrase is asked only once.
+	openedKeystore *secrets.Keystore
+	// keystoreMu guards openedKeystore, providers are created concurrently by compare and --race.
+	keystoreMu sync.Mutex
+)
+
+// openKeystore opens the keystore configured in the global config.
+// If create is true and the keystore does not exist yet, the passphrase read from the terminal
+// is asked twice, since a mistyped passphrase of a new keystore would lock its secrets away.
+func openKeystore(ctx context.Context, create bool) (*secrets.Keystore, error) {
+	keystoreMu.Lock()
+	defer keystoreMu.Unlock()
+	if openedKeystore != nil {
+		return openedKeystore, nil
+	}
+	fs := GetFs(ctx)
+	path := os.ExpandEnv(globalConfig.Keystore.Get())
+	passphrase, ok := os.LookupEnv(keystorePassphraseEnv)
+	if !ok {
+		var err error
+		if passphrase, err = readPassword("Keystore passphrase: "); err != nil {
+			return nil, err
+		}
+		if create {
+			if err = confirmNewPassphrase(fs, path, passphrase); err != nil {
+				return nil, err
+			}
+		}
+	}
+	keystore, err := secrets.OpenKeystore(fs, path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	openedKeystore = keystore
+	return keystore, nil
+}
+
+// confirmNewPassphrase asks for the passphrase again if the keystore does not exist.
+func confirmNewPassphrase(fs afero.Fs, path, passphrase string) error {
+	exists, err := afero.Exists(fs, path)
+	if err != nil {
+		return fmt.Errorf("failed to check keystore %s: %w", path, err)
+	}
+	if exists {
+		return nil
+	}
+	repeated, err := readPassword("Repeat the passphrase of the new keystore: ")
+	if err != nil {
+		return err
+	}
+	if repeated != passphrase {
+		return errs.New(errPassphraseMismatch, "The passphrases do not match, the keystore was not created")
+	}
+	return nil
+}
+
+// readPassword reads a password from the terminal without echo.
+func readPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%w, set %s", errNoTerminal, keystorePassphraseEnv)
+	}
+	_, _ = fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimSpace(string(password)), nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// integrationStart and integrationEnd mark the shell integration in the shell rc file.
+	integrationStart = "# >>> aai shell integration >>>"
+	integrationEnd   = "# <<< aai shell integration <<<"
+)
+
+var (
+	// errNoIntegration is returned when there is no shell integration for the shell
+	errNoIntegration = errors.New("shell integration is not available")
+
+	// integrations are the shell integration scripts. They define the "aai-run" function,
+	// which puts the suggested command in the command line, to be edited before it is run.
+	// The command is checked with "aai check" first, bash asks to type "yes" before it runs
+	// a dangerous command, zsh prints the findings above the command line.
+	integrations = map[shell.Dialect]string{
+		shell.Bash: `aai-run() {
+  local cmd answer
+  cmd="$(aai "$@")" || return
+  read -r -e -i "$cmd" -p "$ " cmd || return
+  if ! aai check -- "$cmd"; then
+    read -r -p "Type yes to run it anyway: " answer
+    [ "$answer" = yes ] || return 1
+  fi
+  history -s "$cmd" && eval "$cmd"
+}`,
+		shell.Zsh: `aai-run() {
+  local cmd
+  cmd="$(aai "$@")" || return
+  aai check -- "$cmd"
+  print -z -- "$cmd"
+}`,
+	}
+
+	// rcFiles are the startup files of the shells, relative to $HOME.
+	rcFiles = map[shell.Dialect]string{
+		shell.Bash: ".bashrc",
+		shell.Zsh:  ".zshrc",
+	}
+)
+
+// detectShell returns the dialect of the user's login shell, or bash.
+func detectShell() shell.Dialect {
+	dialect, err := shell.ParseDialect(filepath.Base(os.Getenv("SHELL")))
+	if err != nil {
+		return shell.Bash
+	}
+	return dialect
+}
+
+// rcFile returns the path of the shell startup file, where the integration is installed.
+func rcFile(dialect shell.Dialect) (string, error) {
+	name, ok := rcFiles[dialect]
+	if !ok {
+		return "", fmt.Errorf("%w for %s", errNoIntegration, dialect)
+	}
+	return filepath.Join(os.Getenv("HOME"), name), nil
+}
+
+// integrationBlock returns the integration script of the dialect between its markers.
+func integrationBlock(dialect shell.Dialect) string {
+	return fmt.Sprintf("%s\n%s\n%s", integrationStart, integrations[dialect], integrationEnd)
+}
+
+// shellIntegrationInstalled returns true if the integration is installed in the shell startup file,
+// and if the installed integration is the current one.
+func shellIntegrationInstalled(fs afero.Fs, dialect shell.Dialect) (installed, current bool, err error) {
+	file, err := rcFile(dialect)
+	if err != nil {
+		return false, false, err
+	}
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return strings.Contains(string(data), integrationStart), strings.Contains(string(data), integrationBlock(dialect)), nil
+}
+
+// installShellIntegration appends the integration to the shell startup file, or replaces
+// an outdated installed integration. It returns the path of the file.
+func installShellIntegration(fs afero.Fs, dialect shell.Dialect) (string, error) {
+	file, err := rcFile(dialect)
+	if err != nil {
+		return "", err
+	}
+	installed, current, err := shellIntegrationInstalled(fs, dialect)
+	if err != nil || current {
+		return file, err
+	}
+
+	if installed {
+		data, err := afero.ReadFile(fs, file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		content := string(data)
+		start := strings.Index(content, integrationStart)
+		end := strings.Index(content[start:], integrationEnd)
+		if end < 0 {
+			return "", fmt.Errorf("failed to update %s: %q has no end marker %q", file, integrationStart, integrationEnd)
+		}
+		content = content[:start] + integrationBlock(dialect) + content[start+end+len(integrationEnd):]
+		if err = afero.WriteFile(fs, file, []byte(content), 0644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		return file, nil
+	}
+
+	f, err := fs.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err = fmt.Fprintf(f, "\n%s\n", integrationBlock(dialect)); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return file, nil
+}
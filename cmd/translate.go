@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/config/flags"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/errs"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errSameDialect is returned when the source and target dialects are the same
+	errSameDialect = errors.New("source and target dialects are the same")
+)
+
+// translateCmd represents the translate command
+var translateCmd = &cobra.Command{
+	Use:   "translate <command>",
+	Short: "Translate a command between shell dialects",
+	Long: fmt.Sprintf(`This command will translate a command or a short script
+from one shell dialect to another.
+Supported dialects: %s.
+
+Example:
+	$ aai translate --to fish "export FOO=bar"
+	set -gx FOO bar
+`, dialectNames()),
+	Args: maxOneArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, err := shell.ParseDialect(translateCmdConfig.From.Get())
+		if err != nil {
+			return errs.New(err, fmt.Sprintf("Unknown source dialect, use one of: %s", dialectNames()))
+		}
+		to, err := shell.ParseDialect(translateCmdConfig.To.Get())
+		if err != nil {
+			return errs.New(err, fmt.Sprintf("Unknown target dialect, use one of: %s", dialectNames()))
+		}
+		if from == to {
+			return errs.New(errSameDialect, "Please provide different source and target dialects")
+		}
+
+		in, err := readInput(cmd, args, translateCmdConfig.InputCmdConfig, errs.New(errNoCommandArg, "Please provide a command to translate"))
+		if err != nil {
+			return err
+		}
+		if err = shell.Validate(in.Text, from); err != nil {
+			return errs.New(err, fmt.Sprintf("The command is not valid %s: %v", from, err))
+		}
+
+		translator, err := newProvider(cmd.Context(), globalConfig.Provider.Get(), providerOptions{
+			context: in.Context,
+		})
+		if err != nil {
+			return err
+		}
+
+		response, err := translator.Translate(in.Text, from, to)
+		if err != nil {
+			return fmt.Errorf("failed to translate a command: %w", err)
+		}
+		fmt.Println(response)
+
+		if err = shell.Validate(response, to); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+		}
+		return nil
+	},
+}
+
+type Translator interface {
+	// Translate returns a command translated from one shell dialect to another.
+	Translate(command string, from, to shell.Dialect) (string, error)
+}
+
+type TranslateCmdConfig struct {
+	InputCmdConfig
+
+	From flags.Flag[string]
+	To   flags.Flag[string]
+}
+
+var translateCmdConfig TranslateCmdConfig
+
+func init() {
+	rootCmd.AddCommand(translateCmd)
+	translateCmdConfig = TranslateCmdConfig{
+		InputCmdConfig: newInputCmdConfig(translateCmd),
+
+		From: flags.String(translateCmd.Flags(), "from", string(shell.Bash), "dialect of the command"),
+		To:   flags.String(translateCmd.Flags(), "to", "", "dialect to translate the command to"),
+	}
+	if err := translateCmd.MarkFlagRequired("to"); err != nil {
+		panic(err)
+	}
+}
+
+// dialectNames returns a comma separated list of supported dialects.
+func dialectNames() string {
+	return strings.Join(dialectList(), ", ")
+}
+
+// dialectList returns the names of the supported dialects.
+func dialectList() []string {
+	names := make([]string, 0, len(shell.Dialects))
+	for _, dialect := range shell.Dialects {
+		names = append(names, string(dialect))
+	}
+	return names
+}
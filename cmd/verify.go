@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/grounding"
+
+	"github.com/rs/zerolog/log"
+)
+
+// verifySuggestion checks the flags of the suggested command against the local
+// documentation of its programs and reports unknown flags to w. If fix is true and
+// there are unknown flags, the query is sent again once, together with the discrepancy.
+// It returns the final suggestion.
+func verifySuggestion(ctx context.Context, w io.Writer, suggester Suggester, query, suggestion string, fix bool) (string, error) {
+	unknown, err := grounding.Verify(ctx, suggestion, globalConfig.GroundHelp.Get())
+	if err != nil {
+		// The suggestion is not a valid shell command, there is nothing to verify.
+		log.Debug().Err(err).Msg("cannot verify suggestion")
+		return suggestion, nil
+	}
+
+	if len(unknown) > 0 && fix {
+		log.Info().Int("count", len(unknown)).Msg("unknown flags found, asking again")
+		suggestion, err = suggester.Suggest(fixQuery(query, unknown))
+		if err != nil {
+			return "", fmt.Errorf("failed to suggest a fixed command: %w", err)
+		}
+		if unknown, err = grounding.Verify(ctx, suggestion, globalConfig.GroundHelp.Get()); err != nil {
+			log.Debug().Err(err).Msg("cannot verify fixed suggestion")
+			return suggestion, nil
+		}
+	}
+
+	for _, u := range unknown {
+		_, _ = fmt.Fprintf(w, "warning: %s\n", u)
+	}
+	return suggestion, nil
+}
+
+// fixQuery returns the query extended with the list of unknown flags.
+func fixQuery(query string, unknown []grounding.UnknownFlag) string {
+	flags := make([]string, 0, len(unknown))
+	for _, u := range unknown {
+		flags = append(flags, fmt.Sprintf("%s %s", u.Program, u.Flag))
+	}
+	return fmt.Sprintf("%s (these flags do not exist on this system: %s)", query, strings.Join(flags, ", "))
+}
@@ -0,0 +1,276 @@
+// Package cassette records HTTP interactions to yaml files and replays them,
+// so that API clients can be tested offline and deterministically.
+//
+// A cassette is an http.RoundTripper. By default it replays the recorded interactions
+// and fails requests that were not recorded. With AAI_RECORD=1 it sends the requests
+// with the real transport, e.g. to a local stand-in server, and records them.
+// API keys are never written to cassettes: credential headers are masked,
+// and the values passed to Scrub are masked in URLs and bodies.
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RecordEnv is the environment variable that enables the record mode when set to "1".
+	RecordEnv = "AAI_RECORD"
+)
+
+var (
+	// ErrNoInteraction is returned when a replayed request was not recorded in the cassette.
+	ErrNoInteraction = errors.New("no recorded interaction")
+
+	// credentialHeaders are the headers that are masked in recorded requests.
+	credentialHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "Openai-Organization"}
+	// responseHeaders are the recorded response headers. Other headers, such as dates
+	// and request ids, change with every request.
+	responseHeaders = []string{"Content-Type"}
+)
+
+// Request is a recorded request. Requests are matched by method, path and body.
+type Request struct {
+	Method  string            `yaml:"method"`
+	Path    string            `yaml:"path"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int               `yaml:"status"`
+	Headers    map[string]string `yaml:"headers,omitempty"`
+	Body       string            `yaml:"body,omitempty"`
+}
+
+// Interaction is a request with its response.
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+// Cassette is a list of interactions stored in a yaml file.
+type Cassette struct {
+	// fs is the file system that holds the cassette file.
+	fs afero.Fs
+	// path is the path of the cassette file.
+	path string
+	// recording is true if the requests are sent and recorded instead of replayed.
+	recording bool
+	// transport sends the requests in the record mode.
+	transport http.RoundTripper
+	// secrets are masked in recorded URLs and bodies.
+	secrets []string
+
+	// mu guards Interactions and replayed.
+	mu sync.Mutex
+	// replayed marks the interactions that were already replayed, by index.
+	replayed map[int]bool
+
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Recording returns true if the record mode is enabled with AAI_RECORD=1.
+func Recording() bool {
+	return os.Getenv(RecordEnv) == "1"
+}
+
+// Open loads the cassette from the provided path to replay it. In the record mode,
+// it returns an empty cassette that sends the requests with the transport,
+// or http.DefaultTransport if it is nil, and overwrites the file on Save.
+func Open(fs afero.Fs, path string, transport http.RoundTripper) (*Cassette, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	c := &Cassette{
+		fs:        fs,
+		path:      path,
+		recording: Recording(),
+		transport: transport,
+		replayed:  make(map[int]bool),
+	}
+	if c.recording {
+		return c, nil
+	}
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette %s, record it with %s=1: %w", path, RecordEnv, err)
+	}
+	if err = yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Scrub masks the values, such as API keys, in the recorded URLs and bodies.
+func (c *Cassette) Scrub(values ...string) {
+	for _, value := range values {
+		if value != "" {
+			c.secrets = append(c.secrets, value)
+		}
+	}
+}
+
+// RoundTrip replays the recorded response of the request, or sends and records it in the record mode.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	recorded := Request{
+		Method:  req.Method,
+		Path:    c.scrub(req.URL.RequestURI()),
+		Headers: c.requestHeaders(req.Header),
+		Body:    c.scrub(body),
+	}
+
+	if !c.recording {
+		return c.replay(req, recorded)
+	}
+
+	res, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := readBody(&res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Headers:    responseHeaderValues(res.Header),
+			Body:       c.scrub(resBody),
+		},
+	})
+	return res, nil
+}
+
+// replay returns the response of the first interaction that matches the request and was not replayed yet.
+func (c *Cassette) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, interaction := range c.Interactions {
+		if c.replayed[i] || !interaction.Request.matches(recorded) {
+			continue
+		}
+		c.replayed[i] = true
+		res := &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        make(http.Header),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}
+		for k, v := range interaction.Response.Headers {
+			res.Header.Set(k, v)
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s in %s", ErrNoInteraction, recorded.Method, recorded.Path, c.path)
+}
+
+// Save writes the recorded interactions to the cassette file, creating directories if needed.
+// It does nothing if the cassette is replayed or nothing was recorded, so that the previous file is kept.
+func (c *Cassette) Save() error {
+	if !c.recording {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.Interactions) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err = c.fs.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(c.path), err)
+	}
+	if err = afero.WriteFile(c.fs, c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// matches returns true if the requests have the same method, path and body.
+// Headers are not compared, since they depend on the environment.
+func (r Request) matches(other Request) bool {
+	return r.Method == other.Method && r.Path == other.Path && r.Body == other.Body
+}
+
+// scrub returns the text with the secrets masked.
+func (c *Cassette) scrub(text string) string {
+	for _, secret := range c.secrets {
+		text = strings.ReplaceAll(text, secret, secrets.Masked)
+	}
+	return text
+}
+
+// requestHeaders returns the first value of every request header with the secrets masked.
+// The credential headers are replaced with secrets.Masked.
+func (c *Cassette) requestHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(header))
+	for k := range header {
+		headers[k] = c.scrub(header.Get(k))
+	}
+	for _, k := range credentialHeaders {
+		if _, ok := headers[k]; ok {
+			headers[k] = secrets.Masked
+		}
+	}
+	return headers
+}
+
+// responseHeaderValues returns the first value of the recorded response headers.
+func responseHeaderValues(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for _, k := range responseHeaders {
+		if v := header.Get(k); v != "" {
+			headers[k] = v
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// readBody reads the body and replaces it with a copy, so that it can be read again.
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	data, err := io.ReadAll(*body)
+	_ = (*body).Close()
+	if err != nil {
+		return "", err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
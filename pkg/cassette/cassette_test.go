@@ -0,0 +1,124 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+
+	"github.com/spf13/afero"
+)
+
+const testKey = "sk-secret"
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Request-Id", "42")
+		_, _ = w.Write([]byte("echo " + string(body)))
+	}))
+	defer server.Close()
+	fs := afero.NewMemMapFs()
+
+	t.Setenv(RecordEnv, "1")
+	recorder, err := Open(fs, "cassettes/test.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Scrub(testKey)
+	if got := send(t, recorder, server.URL+"/v1/completions?key="+testKey, "hello "+testKey); got != "echo hello "+testKey {
+		t.Errorf("recorded response = %q, want the server response", got)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := afero.ReadFile(fs, "cassettes/test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), testKey) {
+		t.Errorf("cassette contains the key:\n%s", data)
+	}
+	if strings.Contains(string(data), "X-Request-Id") {
+		t.Errorf("cassette contains a volatile response header:\n%s", data)
+	}
+
+	t.Setenv(RecordEnv, "")
+	replayer, err := Open(fs, "cassettes/test.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer.Scrub(testKey)
+	server.Close()
+	want := "echo hello " + secrets.Masked
+	if got := send(t, replayer, "http://stand-in/v1/completions?key="+testKey, "hello "+testKey); got != want {
+		t.Errorf("replayed response = %q, want %q", got, want)
+	}
+
+	// Every interaction is replayed once.
+	_, err = replayer.RoundTrip(newRequest(t, "http://stand-in/v1/completions?key="+testKey, "hello "+testKey))
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("RoundTrip() error = %v, want %v", err, ErrNoInteraction)
+	}
+}
+
+func TestCassette_SaveNothingRecorded(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "test.yaml", []byte("interactions: [recorded]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(RecordEnv, "1")
+	recorder, err := Open(fs, "test.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := afero.ReadFile(fs, "test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "interactions: [recorded]\n" {
+		t.Errorf("cassette = %q, want the previous cassette", data)
+	}
+}
+
+func TestOpen_Missing(t *testing.T) {
+	t.Setenv(RecordEnv, "")
+	if _, err := Open(afero.NewMemMapFs(), "missing.yaml", nil); err == nil {
+		t.Error("Open() of a missing cassette error = nil, want an error")
+	}
+}
+
+// send sends a POST request with the body through the cassette and returns the response body.
+func send(t *testing.T, c *Cassette, url, body string) string {
+	t.Helper()
+	res, err := (&http.Client{Transport: c}).Do(newRequest(t, url, body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func newRequest(t *testing.T, url, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testKey)
+	return req
+}
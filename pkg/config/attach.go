@@ -10,11 +10,16 @@ type attachable interface {
 	Attach(config *viper.Viper) error
 }
 
-// Attach attaches config to the configValue and executes post attach functions,
-// such as binding the flag to the config.
+// Attach attaches config to the configValue, binds its environment variables
+// and executes post attach functions, such as binding the flag to the config.
 func (v *configValue[T]) Attach(config *viper.Viper) error {
 	v.config = config
 
+	envs := append([]string{EnvName(v.key)}, v.envs...)
+	if err := config.BindEnv(append([]string{v.key}, envs...)...); err != nil {
+		return fmt.Errorf("failed to bind env %v to config key %q: %w", envs, v.key, err)
+	}
+
 	for _, postAttach := range v.postAttach {
 		if err := postAttach(); err != nil {
 			return err
@@ -1,24 +1,49 @@
 // Package config is a wrapper around viper that provides
 // a consistent way to access configuration values.
+//
+// A value is resolved from the following sources, in order of precedence:
+//  1. flag, if it was set on the command line (WithFlag)
+//  2. environment variable, the automatic AAI_ variable (see EnvName)
+//     or one of the variables bound with WithEnv
+//  3. profile, the values of the selected profile (see ApplyProfile)
+//  4. config file
+//  5. default value of the flag or WithDefault
 package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+const (
+	// EnvPrefix is the prefix of the automatic environment variables.
+	EnvPrefix = "AAI"
+)
+
+// envKeyReplacer replaces config key separators with environment variable separators.
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
 // Value is an interface that can be used to get values from a config.
 type Value[T any] interface {
 	Key() string
 	IsSet() bool
 	Get() T
 	Set(T)
+	Flag() *pflag.Flag
+	IsSecret() bool
 }
 
 // viperGetter is a function that returns a value from the viper config.
 type viperGetter[T any] func(*viper.Viper, string) T
 
+// caster is a function that converts a raw config value to type T, or returns an error.
+type caster[T any] func(any) (T, error)
+
 // pflagSetter is a function that creates a flag for the provided flag set.
 type pflagSetter[T any] func(f *pflag.FlagSet, name, shorthand string, value T, usage string) *T
 
@@ -31,12 +56,20 @@ type configValue[T any] struct {
 	key string
 	// getter is a function that returns the value associated with the key as a type T.
 	getter viperGetter[T]
+	// caster is a function that converts a raw value to type T, used by validation.
+	caster caster[T]
 	// flagSetter is a function that creates a flag for the provided flag set.
 	flagSetter pflagSetter[T]
 	// flag is the optional flag associated with the value.
 	flag *pflag.Flag
 	// flagValue is the optional flag value associated with the value.
 	flagValue *T
+	// envs are the additional environment variables bound to the value.
+	envs []string
+	// validators are the functions that check the value, see WithValidate.
+	validators []func(T) error
+	// secret marks values that must be masked when displayed or logged.
+	secret bool
 
 	// postAttach is a list of functions that are executed after Attach method is called.
 	postAttach []func() error
@@ -58,6 +91,16 @@ func (v *configValue[T]) IsSet() bool {
 	return v.config.IsSet(v.key)
 }
 
+// Flag returns the flag associated with the value, or nil if there is none.
+func (v *configValue[T]) Flag() *pflag.Flag {
+	return v.flag
+}
+
+// IsSecret returns true if the value is sensitive, see WithSecret.
+func (v *configValue[T]) IsSecret() bool {
+	return v.secret
+}
+
 type Option[T any] func(*configValue[T])
 
 // WithFlagP is like WithFlag, but accepts a shorthand letter that can be used after a single dash.
@@ -80,6 +123,28 @@ func WithFlag[T any](f *pflag.FlagSet, name string, value T, usage string) Optio
 	return WithFlagP(f, name, "", value, usage)
 }
 
+// WithEnv binds additional environment variables to the value, e.g. OPENAI_API_KEY.
+// They are checked in the provided order, after the automatic AAI_ variable.
+func WithEnv[T any](names ...string) Option[T] {
+	return func(v *configValue[T]) {
+		v.envs = append(v.envs, names...)
+	}
+}
+
+// WithSecret marks the value as sensitive, e.g. an API key.
+// Secret values are masked in config views and logs.
+func WithSecret[T any]() Option[T] {
+	return func(v *configValue[T]) {
+		v.secret = true
+	}
+}
+
+// EnvName returns the automatic environment variable name of the config key,
+// e.g. "providers.openai.apikey" returns "AAI_PROVIDERS_OPENAI_APIKEY".
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
 // WithDefault sets the default value for the config value.
 func WithDefault[T any](value T) Option[T] {
 	return func(v *configValue[T]) {
@@ -88,10 +153,11 @@ func WithDefault[T any](value T) Option[T] {
 }
 
 // newValue creates a new config configValue.
-func newValue[T any](key string, getter viperGetter[T], flagSetter pflagSetter[T], options ...Option[T]) *configValue[T] {
+func newValue[T any](key string, getter viperGetter[T], caster caster[T], flagSetter pflagSetter[T], options ...Option[T]) *configValue[T] {
 	value := configValue[T]{
 		key:        key,
 		getter:     getter,
+		caster:     caster,
 		flagSetter: flagSetter,
 	}
 	for _, option := range options {
@@ -102,15 +168,38 @@ func newValue[T any](key string, getter viperGetter[T], flagSetter pflagSetter[T
 
 // String creates a new config configValue of type string.
 func String(key string, options ...Option[string]) Value[string] {
-	return newValue(key, (*viper.Viper).GetString, (*pflag.FlagSet).StringP, options...)
+	return newValue(key, (*viper.Viper).GetString, cast.ToStringE, (*pflag.FlagSet).StringP, options...)
 }
 
 // Int creates a new config configValue of type int.
 func Int(key string, options ...Option[int]) Value[int] {
-	return newValue(key, (*viper.Viper).GetInt, (*pflag.FlagSet).IntP, options...)
+	return newValue(key, (*viper.Viper).GetInt, cast.ToIntE, (*pflag.FlagSet).IntP, options...)
 }
 
 // Float64 creates a new config configValue of type float64.
 func Float64(key string, options ...Option[float64]) Value[float64] {
-	return newValue(key, (*viper.Viper).GetFloat64, (*pflag.FlagSet).Float64P, options...)
+	return newValue(key, (*viper.Viper).GetFloat64, cast.ToFloat64E, (*pflag.FlagSet).Float64P, options...)
+}
+
+// Bool creates a new config configValue of type bool.
+func Bool(key string, options ...Option[bool]) Value[bool] {
+	return newValue(key, (*viper.Viper).GetBool, cast.ToBoolE, (*pflag.FlagSet).BoolP, options...)
+}
+
+// Duration creates a new config configValue of type time.Duration.
+// Values are parsed with time.ParseDuration, e.g. "30s" or "1m30s".
+func Duration(key string, options ...Option[time.Duration]) Value[time.Duration] {
+	return newValue(key, (*viper.Viper).GetDuration, cast.ToDurationE, (*pflag.FlagSet).DurationP, options...)
+}
+
+// StringSlice creates a new config configValue of type []string.
+// The flag accepts comma separated values and can be repeated.
+func StringSlice(key string, options ...Option[[]string]) Value[[]string] {
+	return newValue(key, (*viper.Viper).GetStringSlice, cast.ToStringSliceE, (*pflag.FlagSet).StringSliceP, options...)
+}
+
+// StringMap creates a new config configValue of type map[string]string.
+// The flag accepts comma separated key=value pairs.
+func StringMap(key string, options ...Option[map[string]string]) Value[map[string]string] {
+	return newValue(key, (*viper.Viper).GetStringMapString, cast.ToStringMapStringE, (*pflag.FlagSet).StringToStringP, options...)
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestValue_Precedence(t *testing.T) {
+	const key = "providers.openai.model"
+	tests := []struct {
+		name string
+		// flag, env, extraEnv, profile and file are the values of every layer, empty values are not set.
+		flag     string
+		env      string
+		extraEnv string
+		profile  string
+		file     string
+		want     string
+		source   Source
+	}{
+		{name: "default", want: "default", source: SourceDefault},
+		{name: "file", file: "file", want: "file", source: SourceFile},
+		{name: "profile over file", profile: "profile", file: "file", want: "profile", source: SourceProfile},
+		{name: "env over profile", env: "env", profile: "profile", file: "file", want: "env", source: SourceEnv},
+		{name: "bound env over profile", extraEnv: "extra", profile: "profile", file: "file", want: "extra", source: SourceEnv},
+		{name: "automatic env over bound env", env: "env", extraEnv: "extra", want: "env", source: SourceEnv},
+		{name: "flag over env", flag: "flag", env: "env", profile: "profile", file: "file", want: "flag", source: SourceFlag},
+		{name: "flag", flag: "flag", want: "flag", source: SourceFlag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvName(key), tt.env)
+			t.Setenv("AAI_TEST_MODEL", tt.extraEnv)
+
+			var file strings.Builder
+			if tt.file != "" {
+				file.WriteString("providers:\n  openai:\n    model: " + tt.file + "\n")
+			}
+			profile := ""
+			if tt.profile != "" {
+				profile = "test"
+				file.WriteString("profiles:\n  test:\n    providers:\n      openai:\n        model: " + tt.profile + "\n")
+			}
+			cfg := viper.New()
+			cfg.SetConfigType("yaml")
+			if err := cfg.ReadConfig(strings.NewReader(file.String())); err != nil {
+				t.Fatal(err)
+			}
+
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			value := String(key, WithFlag(flags, "model", "default", "model"), WithEnv[string]("AAI_TEST_MODEL"))
+			if tt.flag != "" {
+				if err := flags.Set("model", tt.flag); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := value.(*configValue[string]).Attach(cfg); err != nil {
+				t.Fatal(err)
+			}
+			if profile != "" {
+				if err := ApplyProfile(cfg, profile); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := value.Get(); got != tt.want {
+				t.Errorf("Get() = %q, want %q", got, tt.want)
+			}
+			if got := value.(*configValue[string]).Source(profile); got != tt.source {
+				t.Errorf("Source() = %q, want %q", got, tt.source)
+			}
+		})
+	}
+}
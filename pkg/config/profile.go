@@ -0,0 +1,48 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// ProfilesKey is the config key of the profiles section.
+	ProfilesKey = "profiles"
+)
+
+var (
+	// ErrUnknownProfile is returned when the selected profile is not defined in the config.
+	ErrUnknownProfile = errors.New("unknown profile")
+)
+
+// ProfileKey returns the config key of the given key in the named profile.
+func ProfileKey(profile, key string) string {
+	return fmt.Sprintf("%s.%s.%s", ProfilesKey, profile, key)
+}
+
+// Profiles returns the sorted names of profiles defined in the config.
+func Profiles(config *viper.Viper) []string {
+	profiles := make([]string, 0)
+	for name := range config.GetStringMap(ProfilesKey) {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// ApplyProfile merges the values of the named profile over the config file values.
+// Profile values override values from the config file, but not values set
+// with flags or environment variables.
+func ApplyProfile(config *viper.Viper, profile string) error {
+	sub := config.Sub(fmt.Sprintf("%s.%s", ProfilesKey, profile))
+	if sub == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownProfile, profile)
+	}
+	if err := config.MergeConfigMap(sub.AllSettings()); err != nil {
+		return fmt.Errorf("failed to merge profile %q: %w", profile, err)
+	}
+	return nil
+}
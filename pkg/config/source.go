@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+)
+
+// Source is where the effective value of a config value comes from.
+type Source string
+
+const (
+	// SourceFlag is a value set with a command line flag.
+	SourceFlag Source = "flag"
+	// SourceEnv is a value set with an environment variable.
+	SourceEnv Source = "env"
+	// SourceProfile is a value set in the selected profile.
+	SourceProfile Source = "profile"
+	// SourceFile is a value set in the config file.
+	SourceFile Source = "file"
+	// SourceDefault is the default value.
+	SourceDefault Source = "default"
+)
+
+// Source returns where the effective value comes from, in the order of precedence.
+// The profile is the name of the applied profile, or an empty string.
+func (v *configValue[T]) Source(profile string) Source {
+	if v.flag != nil && v.flag.Changed {
+		return SourceFlag
+	}
+	for _, env := range append([]string{EnvName(v.key)}, v.envs...) {
+		if os.Getenv(env) != "" {
+			return SourceEnv
+		}
+	}
+	if profile != "" && v.config.IsSet(ProfileKey(profile, v.key)) {
+		return SourceProfile
+	}
+	if v.config.InConfig(v.key) {
+		return SourceFile
+	}
+	return SourceDefault
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -15,6 +18,12 @@ var (
 type AnyValue interface {
 	Key() string
 	IsSet() bool
+	Flag() *pflag.Flag
+	IsSecret() bool
+	Validate() error
+	ValidateIn(config *viper.Viper, key string) error
+	Source(profile string) Source
+	ParseAny(text string) (any, error)
 
 	GetAny() any
 	SetAny(any)
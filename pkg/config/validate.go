@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrOutOfRange is returned when a value is outside of the range set with WithRange.
+	ErrOutOfRange = errors.New("value out of range")
+	// ErrNotAllowed is returned when a value is not one of the values set with WithEnum.
+	ErrNotAllowed = errors.New("value not allowed")
+)
+
+// ValidationError is an invalid config value.
+type ValidationError struct {
+	// Key is the config key of the value, e.g. "providers.openai.temperature" or a key in a profile.
+	Key string
+	// Value is the invalid value.
+	Value any
+	// Err is the reason why the value is invalid.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid value %v of %s: %v", e.Value, e.Key, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// number is a constraint of the numeric value types.
+type number interface {
+	~int | ~int64 | ~float64
+}
+
+// WithValidate adds a validator to the value. Validators run in the order they were added.
+func WithValidate[T any](validate func(T) error) Option[T] {
+	return func(v *configValue[T]) {
+		v.validators = append(v.validators, validate)
+	}
+}
+
+// WithRange validates that the value is between min and max, inclusive.
+func WithRange[T number](min, max T) Option[T] {
+	return WithValidate(func(value T) error {
+		if value < min || value > max {
+			return fmt.Errorf("%w, must be between %v and %v", ErrOutOfRange, min, max)
+		}
+		return nil
+	})
+}
+
+// WithEnum validates that the value is one of the allowed values.
+func WithEnum[T comparable](allowed ...T) Option[T] {
+	return WithValidate(func(value T) error {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w, must be one of %v", ErrNotAllowed, allowed)
+	})
+}
+
+// Validate validates the value in the attached config.
+func (v *configValue[T]) Validate() error {
+	return v.ValidateIn(v.config, v.key)
+}
+
+// ValidateIn validates the value stored under the key in the provided config,
+// e.g. a config file or a profile. Values that are not set are valid.
+func (v *configValue[T]) ValidateIn(config *viper.Viper, key string) error {
+	raw := config.Get(key)
+	if raw == nil {
+		return nil
+	}
+	value, err := v.caster(raw)
+	if err != nil {
+		return &ValidationError{Key: key, Value: raw, Err: fmt.Errorf("expected %T", value)}
+	}
+	for _, validate := range v.validators {
+		if err = validate(value); err != nil {
+			return &ValidationError{Key: key, Value: raw, Err: err}
+		}
+	}
+	return nil
+}
+
+// Parse parses the text with the flag syntax of the value, e.g. "a,b" for string slices
+// or "1m30s" for durations, and validates the result.
+func (v *configValue[T]) Parse(text string) (T, error) {
+	var zero T
+	flags := pflag.NewFlagSet(v.key, pflag.ContinueOnError)
+	value := v.flagSetter(flags, v.key, "", zero, "")
+	if err := flags.Lookup(v.key).Value.Set(text); err != nil {
+		return zero, &ValidationError{Key: v.key, Value: text, Err: fmt.Errorf("expected %T", zero)}
+	}
+	for _, validate := range v.validators {
+		if err := validate(*value); err != nil {
+			return zero, &ValidationError{Key: v.key, Value: text, Err: err}
+		}
+	}
+	return *value, nil
+}
+
+// ParseAny is a helper function that returns the result of Parse as a type any.
+func (v *configValue[T]) ParseAny(text string) (any, error) {
+	return v.Parse(text)
+}
+
+// Validate validates all values of the provided struct in their attached config
+// and returns the invalid ones. The input must be a pointer to a struct or a struct.
+func Validate(input interface{}) ([]*ValidationError, error) {
+	var invalid []*ValidationError
+	err := Traverse(input, func(value AnyValue) error {
+		var validationErr *ValidationError
+		if err := value.Validate(); errors.As(err, &validationErr) {
+			invalid = append(invalid, validationErr)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invalid, nil
+}
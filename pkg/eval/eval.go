@@ -0,0 +1,273 @@
+// Package eval runs suites of queries against providers and checks the suggested commands,
+// e.g. that they match a regular expression, use a program or produce the expected output.
+package eval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// sandboxTimeout is the maximum run time of a command in the sandbox.
+	sandboxTimeout = 10 * time.Second
+)
+
+var (
+	// ErrInvalidSuite is returned when the suite file has invalid cases.
+	ErrInvalidSuite = errors.New("invalid suite")
+)
+
+// Suite is a list of cases stored in a yaml file.
+type Suite struct {
+	// Targets are the providers evaluated by default, e.g. "openai:text-davinci-003".
+	Targets []string `yaml:"targets,omitempty"`
+	Cases   []Case   `yaml:"cases"`
+}
+
+// Case is a query with the checks of the suggested command.
+type Case struct {
+	// Name identifies the case in reports and baselines, the query by default.
+	Name  string `yaml:"name,omitempty"`
+	Query string `yaml:"query"`
+	// Expect is the expected command. Differences in whitespace are ignored.
+	Expect string `yaml:"expect,omitempty"`
+	// Match is a regular expression that the command must match.
+	Match string `yaml:"match,omitempty"`
+	// Parses requires the command to be a valid bash command.
+	Parses bool `yaml:"parses,omitempty"`
+	// Uses are the programs that the command must run.
+	Uses []string `yaml:"uses,omitempty"`
+	// Sandbox runs the command in a temporary directory and checks its output.
+	Sandbox *Sandbox `yaml:"sandbox,omitempty"`
+}
+
+// Sandbox is a temporary directory where the command runs.
+type Sandbox struct {
+	// Files are created before the command runs, contents by relative path.
+	Files map[string]string `yaml:"files,omitempty"`
+	// Output is the expected standard output. Leading and trailing whitespace is ignored.
+	Output string `yaml:"output"`
+}
+
+// CheckResult is the result of a single check of a command.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	// Detail describes why the check failed or was skipped.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Load reads the suite from the provided path and validates its cases.
+func Load(fs afero.Fs, path string) (*Suite, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite %s: %w", path, err)
+	}
+	var suite Suite
+	if err = yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse suite %s: %w", path, err)
+	}
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("%w: no cases in %s", ErrInvalidSuite, path)
+	}
+	names := make(map[string]bool)
+	for i := range suite.Cases {
+		c := &suite.Cases[i]
+		if c.Query == "" {
+			return nil, fmt.Errorf("%w: case %d has no query", ErrInvalidSuite, i+1)
+		}
+		if c.Name == "" {
+			c.Name = c.Query
+		}
+		// Results and baselines are matched by name.
+		if names[c.Name] {
+			return nil, fmt.Errorf("%w: case name %q is not unique", ErrInvalidSuite, c.Name)
+		}
+		names[c.Name] = true
+		if c.Expect == "" && c.Match == "" && !c.Parses && len(c.Uses) == 0 && c.Sandbox == nil {
+			return nil, fmt.Errorf("%w: case %q has no checks", ErrInvalidSuite, c.Name)
+		}
+		if _, err = regexp.Compile(c.Match); err != nil {
+			return nil, fmt.Errorf("%w: case %q: %v", ErrInvalidSuite, c.Name, err)
+		}
+	}
+	return &suite, nil
+}
+
+// Check runs the checks of the case on the command. Sandbox checks run only if sandbox is true,
+// otherwise they are skipped, since they execute the command.
+func (c Case) Check(ctx context.Context, command string, sandbox bool) []CheckResult {
+	var results []CheckResult
+	if c.Expect != "" {
+		result := CheckResult{Name: "expect", Passed: normalize(command) == normalize(c.Expect)}
+		if !result.Passed {
+			result.Detail = fmt.Sprintf("expected %q", c.Expect)
+		}
+		results = append(results, result)
+	}
+	if c.Match != "" {
+		result := CheckResult{Name: "match", Passed: regexp.MustCompile(c.Match).MatchString(command)}
+		if !result.Passed {
+			result.Detail = fmt.Sprintf("does not match %q", c.Match)
+		}
+		results = append(results, result)
+	}
+	nodes, parseErr := shell.Parse(command)
+	if c.Parses {
+		result := CheckResult{Name: "parses", Passed: parseErr == nil}
+		if parseErr != nil {
+			result.Detail = parseErr.Error()
+		}
+		results = append(results, result)
+	}
+	if len(c.Uses) > 0 {
+		results = append(results, checkUses(nodes, c.Uses))
+	}
+	if c.Sandbox != nil {
+		if sandbox {
+			results = append(results, c.Sandbox.check(ctx, command))
+		} else {
+			results = append(results, CheckResult{Name: "sandbox", Skipped: true, Detail: "sandbox checks are disabled"})
+		}
+	}
+	return results
+}
+
+// Passed returns true if none of the checks failed.
+func Passed(results []CheckResult) bool {
+	for _, result := range results {
+		if !result.Passed && !result.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// Skipped returns true if all the checks were skipped.
+func Skipped(results []CheckResult) bool {
+	for _, result := range results {
+		if !result.Skipped {
+			return false
+		}
+	}
+	return len(results) > 0
+}
+
+// checkUses checks that the parsed command runs all the programs.
+func checkUses(nodes []*shell.Node, uses []string) CheckResult {
+	programs := make(map[string]bool)
+	shell.Walk(nodes, func(node *shell.Node, depth int) {
+		if node.Kind == shell.KindProgram {
+			programs[filepath.Base(node.Text)] = true
+		}
+	})
+	var missing []string
+	for _, program := range uses {
+		if !programs[program] {
+			missing = append(missing, program)
+		}
+	}
+	if len(missing) > 0 {
+		return CheckResult{Name: "uses", Detail: fmt.Sprintf("does not use %s", strings.Join(missing, ", "))}
+	}
+	return CheckResult{Name: "uses", Passed: true}
+}
+
+// check runs the command with bash in a new temporary directory with the sandbox files
+// and compares its output. The command runs with a minimal environment, but it is not isolated
+// from the rest of the system.
+func (s *Sandbox) check(ctx context.Context, command string) CheckResult {
+	result := CheckResult{Name: "sandbox"}
+	dir, err := os.MkdirTemp("", "aai-eval-*")
+	if err != nil {
+		result.Detail = fmt.Sprintf("failed to create sandbox: %v", err)
+		return result
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	for name, content := range s.Files {
+		path := filepath.Join(dir, name)
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			result.Detail = fmt.Sprintf("sandbox file %s is outside of the sandbox", name)
+			return result
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = os.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			result.Detail = fmt.Sprintf("failed to create sandbox file %s: %v", name, err)
+			return result
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sandboxTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = sandboxEnv(dir)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	setProcessGroup(cmd)
+	if err = cmd.Start(); err != nil {
+		result.Detail = fmt.Sprintf("failed to run command: %v", err)
+		return result
+	}
+	// The whole process group is killed on timeout, since the children of bash
+	// would keep the output open and the command running.
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+	err = cmd.Wait()
+	close(done)
+	if ctx.Err() != nil {
+		result.Detail = fmt.Sprintf("command did not finish: %v", ctx.Err())
+		return result
+	}
+	if err != nil {
+		result.Detail = fmt.Sprintf("command failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+		return result
+	}
+	output := strings.TrimSpace(stdout.String())
+	result.Passed = output == strings.TrimSpace(s.Output)
+	if !result.Passed {
+		result.Detail = fmt.Sprintf("expected output %q, got %q", strings.TrimSpace(s.Output), output)
+	}
+	return result
+}
+
+// sandboxEnv returns the environment of sandbox commands: the PATH and the LANG of aai,
+// and the sandbox directory as HOME. Other variables, such as API keys, are not passed.
+func sandboxEnv(dir string) []string {
+	env := []string{"HOME=" + dir}
+	for _, name := range []string{"PATH", "LANG"} {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// normalize returns the command with whitespace sequences replaced by single spaces.
+func normalize(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
@@ -0,0 +1,92 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name  string
+		suite string
+		err   error
+	}{
+		{name: "valid", suite: "cases:\n  - query: list files\n    expect: ls\n  - query: list all files\n    expect: ls -a\n"},
+		{name: "no cases", suite: "cases: []\n", err: ErrInvalidSuite},
+		{name: "no query", suite: "cases:\n  - expect: ls\n", err: ErrInvalidSuite},
+		{name: "no checks", suite: "cases:\n  - query: list files\n", err: ErrInvalidSuite},
+		{name: "invalid match", suite: "cases:\n  - query: list files\n    match: '('\n", err: ErrInvalidSuite},
+		{name: "duplicate names", suite: "cases:\n  - query: list files\n    expect: ls\n  - query: list files\n    expect: ls -1\n", err: ErrInvalidSuite},
+		{name: "duplicate explicit names", suite: "cases:\n  - {name: ls, query: list files, expect: ls}\n  - {name: ls, query: list all files, expect: ls -a}\n", err: ErrInvalidSuite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := afero.WriteFile(fs, "suite.yaml", []byte(tt.suite), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(fs, "suite.yaml")
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Load() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCase_Check(t *testing.T) {
+	t.Setenv("AAI_EVAL_SECRET", "secret")
+	sandbox := &Sandbox{Files: map[string]string{"b.txt": "", "a.txt": ""}, Output: "a.txt\nb.txt"}
+	tests := []struct {
+		name    string
+		c       Case
+		command string
+		sandbox bool
+		passed  bool
+		skipped bool
+	}{
+		{name: "expect", c: Case{Expect: "ls  -la"}, command: "ls -la", passed: true},
+		{name: "expect other command", c: Case{Expect: "ls -la"}, command: "ls -l"},
+		{name: "match", c: Case{Match: `^find .* -name`}, command: "find . -name '*.go'", passed: true},
+		{name: "no match", c: Case{Match: `^find`}, command: "ls"},
+		{name: "parses", c: Case{Parses: true}, command: "ls | wc -l", passed: true},
+		{name: "does not parse", c: Case{Parses: true}, command: "ls |"},
+		{name: "uses", c: Case{Uses: []string{"find", "wc"}}, command: "find . -name '*.go' | xargs wc -l", passed: true},
+		{name: "does not use", c: Case{Uses: []string{"wc"}}, command: "ls"},
+		{name: "sandbox disabled", c: Case{Sandbox: sandbox}, command: "ls", passed: true, skipped: true},
+		{name: "sandbox", c: Case{Sandbox: sandbox}, command: "ls *.txt", sandbox: true, passed: true},
+		{name: "sandbox other output", c: Case{Sandbox: sandbox}, command: "ls -r *.txt", sandbox: true},
+		{name: "sandbox command fails", c: Case{Sandbox: sandbox}, command: "false", sandbox: true},
+		{name: "sandbox home", c: Case{Sandbox: &Sandbox{Files: map[string]string{"a.txt": ""}, Output: "a.txt"}}, command: "ls ~", sandbox: true, passed: true},
+		{name: "sandbox environment", c: Case{Sandbox: &Sandbox{Output: ""}}, command: "echo $AAI_EVAL_SECRET", sandbox: true, passed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := tt.c.Check(context.Background(), tt.command, tt.sandbox)
+			if passed := Passed(results); passed != tt.passed {
+				t.Errorf("Passed() = %v, want %v, results: %+v", passed, tt.passed, results)
+			}
+			if skipped := Skipped(results); skipped != tt.skipped {
+				t.Errorf("Skipped() = %v, want %v, results: %+v", skipped, tt.skipped, results)
+			}
+		})
+	}
+}
+
+func TestSandbox_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+
+	// The background sleep keeps the output open, unless the whole process group is killed.
+	result := (&Sandbox{}).check(ctx, "sleep 30 & sleep 30")
+	if result.Passed {
+		t.Error("check() passed, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("check() took %v, want it to stop after the timeout", elapsed)
+	}
+}
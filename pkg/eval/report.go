@@ -0,0 +1,139 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/afero"
+)
+
+// Result is the result of a case with a target.
+type Result struct {
+	Case    string `json:"case"`
+	Target  string `json:"target"`
+	Command string `json:"command,omitempty"`
+	// Error is the error of the provider, if it did not answer.
+	Error  string `json:"error,omitempty"`
+	Passed bool   `json:"passed"`
+	// Skipped is true if all the checks of the case were skipped.
+	Skipped   bool          `json:"skipped,omitempty"`
+	Checks    []CheckResult `json:"checks,omitempty"`
+	LatencyMs int64         `json:"latency_ms"`
+	Tokens    int           `json:"tokens,omitempty"`
+	// Cost is the estimated cost in USD, if the price of the model is known.
+	Cost *float64 `json:"cost_usd,omitempty"`
+}
+
+// Summary is the aggregated result of a target.
+type Summary struct {
+	Target string `json:"target"`
+	Cases  int    `json:"cases"`
+	// Passed is the number of passed cases, without the skipped ones.
+	Passed  int `json:"passed"`
+	Skipped int `json:"skipped"`
+	// Accuracy is the ratio of passed cases, without the skipped ones.
+	Accuracy float64 `json:"accuracy"`
+	// AvgLatencyMs is the average latency of the answers.
+	AvgLatencyMs int64 `json:"avg_latency_ms"`
+	Tokens       int   `json:"tokens"`
+	// Cost is the total estimated cost of the answers with a known price, in USD.
+	Cost float64 `json:"cost_usd"`
+}
+
+// Report is the result of a suite run. It can be saved as the baseline of later runs.
+type Report struct {
+	Summaries []Summary `json:"summaries"`
+	Results   []Result  `json:"results"`
+}
+
+// Regression is a case that passed in the baseline and fails now.
+type Regression struct {
+	Case   string
+	Target string
+	// Baseline is the command that passed in the baseline.
+	Baseline string
+	// Current is the result that fails now.
+	Current Result
+}
+
+// NewReport summarizes the results by target, in the order the targets appear.
+func NewReport(results []Result) *Report {
+	var summaries []*Summary
+	byTarget := make(map[string]*Summary)
+	latencies := make(map[string]int64)
+	for _, result := range results {
+		summary, ok := byTarget[result.Target]
+		if !ok {
+			summary = &Summary{Target: result.Target}
+			byTarget[result.Target] = summary
+			summaries = append(summaries, summary)
+		}
+		summary.Cases++
+		if result.Skipped {
+			summary.Skipped++
+		} else if result.Passed {
+			summary.Passed++
+		}
+		summary.Tokens += result.Tokens
+		if result.Cost != nil {
+			summary.Cost += *result.Cost
+		}
+		latencies[result.Target] += result.LatencyMs
+	}
+
+	report := &Report{Results: results}
+	for _, summary := range summaries {
+		if checked := summary.Cases - summary.Skipped; checked > 0 {
+			summary.Accuracy = float64(summary.Passed) / float64(checked)
+		}
+		summary.AvgLatencyMs = latencies[summary.Target] / int64(summary.Cases)
+		report.Summaries = append(report.Summaries, *summary)
+	}
+	return report
+}
+
+// LoadReport reads a report saved with Save.
+func LoadReport(fs afero.Fs, path string) (*Report, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report %s: %w", path, err)
+	}
+	var report Report
+	if err = json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// Save writes the report to the path as JSON.
+func (r *Report) Save(fs afero.Fs, path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err = afero.WriteFile(fs, path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", path, err)
+	}
+	return nil
+}
+
+// Regressions returns the cases that passed in the baseline and fail in the report, by target.
+// Cases and targets missing in the baseline are not regressions.
+func (r *Report) Regressions(baseline *Report) []Regression {
+	type key struct{ target, name string }
+	passed := make(map[key]string)
+	for _, result := range baseline.Results {
+		if result.Passed {
+			passed[key{result.Target, result.Case}] = result.Command
+		}
+	}
+
+	var regressions []Regression
+	for _, result := range r.Results {
+		command, ok := passed[key{result.Target, result.Case}]
+		if ok && !result.Passed && !result.Skipped {
+			regressions = append(regressions, Regression{Case: result.Case, Target: result.Target, Baseline: command, Current: result})
+		}
+	}
+	return regressions
+}
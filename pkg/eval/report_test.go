@@ -0,0 +1,99 @@
+package eval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewReport(t *testing.T) {
+	cost := 0.5
+	tests := []struct {
+		name    string
+		results []Result
+		want    []Summary
+	}{
+		{name: "no results"},
+		{
+			name: "targets in order",
+			results: []Result{
+				{Case: "a", Target: "openai", Passed: true, LatencyMs: 100, Tokens: 10, Cost: &cost},
+				{Case: "a", Target: "mock", Passed: false, LatencyMs: 10},
+				{Case: "b", Target: "openai", Passed: false, LatencyMs: 300, Tokens: 20, Cost: &cost},
+			},
+			want: []Summary{
+				{Target: "openai", Cases: 2, Passed: 1, Accuracy: 0.5, AvgLatencyMs: 200, Tokens: 30, Cost: 1},
+				{Target: "mock", Cases: 1, AvgLatencyMs: 10},
+			},
+		},
+		{
+			name: "skipped cases",
+			results: []Result{
+				{Case: "a", Target: "openai", Passed: true},
+				{Case: "b", Target: "openai", Passed: true, Skipped: true},
+			},
+			want: []Summary{{Target: "openai", Cases: 2, Passed: 1, Skipped: 1, Accuracy: 1}},
+		},
+		{
+			name:    "all skipped",
+			results: []Result{{Case: "a", Target: "openai", Passed: true, Skipped: true}},
+			want:    []Summary{{Target: "openai", Cases: 1, Skipped: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewReport(tt.results)
+			if !reflect.DeepEqual(report.Summaries, tt.want) {
+				t.Errorf("NewReport().Summaries = %+v, want %+v", report.Summaries, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport_Regressions(t *testing.T) {
+	baseline := NewReport([]Result{
+		{Case: "a", Target: "openai", Command: "ls", Passed: true},
+		{Case: "b", Target: "openai", Command: "ls -l"},
+		{Case: "c", Target: "openai", Command: "ls -a", Passed: true},
+	})
+	tests := []struct {
+		name    string
+		results []Result
+		want    []string
+	}{
+		{
+			name:    "no regressions",
+			results: []Result{{Case: "a", Target: "openai", Passed: true}, {Case: "b", Target: "openai", Passed: true}},
+		},
+		{
+			name:    "passed and fails now",
+			results: []Result{{Case: "a", Target: "openai"}, {Case: "c", Target: "openai", Passed: true}},
+			want:    []string{"a"},
+		},
+		{
+			name:    "failed in the baseline",
+			results: []Result{{Case: "b", Target: "openai"}},
+		},
+		{
+			name:    "skipped now",
+			results: []Result{{Case: "a", Target: "openai", Skipped: true}},
+		},
+		{
+			name:    "missing in the baseline",
+			results: []Result{{Case: "a", Target: "mock"}, {Case: "d", Target: "openai"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, regression := range NewReport(tt.results).Regressions(baseline) {
+				got = append(got, regression.Case)
+				if regression.Baseline != "ls" {
+					t.Errorf("Baseline = %q, want the command of the baseline", regression.Baseline)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Regressions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package eval
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing, process groups are not supported on windows.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the started command, its children are not killed on windows.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,189 @@
+// Package examples manages a personal library of few-shot examples
+// (query/command pairs) that are injected into suggestion prompts.
+package examples
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrIndexOutOfRange is returned when an example index does not exist in the library.
+	ErrIndexOutOfRange = errors.New("example index out of range")
+	// ErrInvalidExample is returned when an example would break the few-shot prompt format.
+	ErrInvalidExample = errors.New("invalid example")
+)
+
+// Default is the example used when the library has no relevant examples.
+var Default = Example{
+	Query:   "create foo directory",
+	Command: "mkdir foo",
+}
+
+// Example is a single query/command pair.
+type Example struct {
+	Query   string `yaml:"query"`
+	Command string `yaml:"command"`
+}
+
+// Validate checks that the query and the command are single, non-empty lines,
+// as every example is a "query:" line followed by an answer line in the prompt.
+func (e Example) Validate() error {
+	if err := validateLine("query", e.Query); err != nil {
+		return err
+	}
+	return validateLine("command", e.Command)
+}
+
+// validateLine returns an error if the value is empty or has more than one line.
+func validateLine(name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("%w: the %s is empty", ErrInvalidExample, name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%w: the %s has more than one line", ErrInvalidExample, name)
+	}
+	return nil
+}
+
+// Library is a list of examples stored in a yaml file.
+type Library struct {
+	// fs is the file system that holds the examples file.
+	fs afero.Fs
+	// path is the path of the examples file.
+	path string
+
+	Examples []Example `yaml:"examples"`
+}
+
+// Load reads the library from the provided path.
+// A missing file is not an error, it results in an empty library.
+func Load(fs afero.Fs, path string) (*Library, error) {
+	lib := &Library{
+		fs:   fs,
+		path: os.ExpandEnv(path),
+	}
+
+	data, err := afero.ReadFile(fs, lib.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return lib, nil
+		}
+		return nil, fmt.Errorf("failed to read examples file %s: %w", lib.path, err)
+	}
+	if err = yaml.Unmarshal(data, lib); err != nil {
+		return nil, fmt.Errorf("failed to parse examples file %s: %w", lib.path, err)
+	}
+	return lib, nil
+}
+
+// Path returns the path of the examples file.
+func (l *Library) Path() string {
+	return l.path
+}
+
+// Save writes the library to its file, creating directories if needed.
+func (l *Library) Save() error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to marshal examples: %w", err)
+	}
+	if err = l.fs.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(l.path), err)
+	}
+	if err = afero.WriteFile(l.fs, l.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write examples file %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// Add appends an example to the library, with the surrounding whitespace trimmed.
+// Invalid examples are rejected.
+func (l *Library) Add(example Example) error {
+	example.Query = strings.TrimSpace(example.Query)
+	example.Command = strings.TrimSpace(example.Command)
+	if err := example.Validate(); err != nil {
+		return err
+	}
+	l.Examples = append(l.Examples, example)
+	return nil
+}
+
+// Remove removes the example at the provided index.
+func (l *Library) Remove(index int) (Example, error) {
+	if index < 0 || index >= len(l.Examples) {
+		return Example{}, fmt.Errorf("%w: %d", ErrIndexOutOfRange, index)
+	}
+	removed := l.Examples[index]
+	l.Examples = append(l.Examples[:index], l.Examples[index+1:]...)
+	return removed, nil
+}
+
+// Select returns at most n examples that are the most relevant to the query.
+// Relevance is the keyword overlap between the query and the example query.
+// Examples without any common keyword are never selected, as well as invalid examples
+// of edited files.
+func (l *Library) Select(query string, n int) []Example {
+	type scored struct {
+		example Example
+		score   float64
+	}
+
+	queryWords := keywords(query)
+	candidates := make([]scored, 0, len(l.Examples))
+	for _, example := range l.Examples {
+		if example.Validate() != nil {
+			continue
+		}
+		score := similarity(queryWords, keywords(example.Query))
+		if score > 0 {
+			candidates = append(candidates, scored{example: example, score: score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	selected := make([]Example, 0, n)
+	for _, candidate := range candidates[:n] {
+		selected = append(selected, candidate.example)
+	}
+	return selected
+}
+
+// keywords returns the set of lowercase words in the text.
+func keywords(text string) map[string]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+// similarity returns the Jaccard index of two keyword sets.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for word := range a {
+		if _, ok := b[word]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
@@ -0,0 +1,98 @@
+package examples
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestLibrary_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		example Example
+		want    Example
+		err     error
+	}{
+		{name: "valid", example: Example{Query: " list files ", Command: "ls\n"}, want: Example{Query: "list files", Command: "ls"}},
+		{name: "empty query", example: Example{Query: " ", Command: "ls"}, err: ErrInvalidExample},
+		{name: "empty command", example: Example{Query: "list files"}, err: ErrInvalidExample},
+		{name: "multi-line command", example: Example{Query: "list files", Command: "cd /tmp\nls"}, err: ErrInvalidExample},
+		{name: "multi-line query", example: Example{Query: "list\r\nfiles", Command: "ls"}, err: ErrInvalidExample},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			library := &Library{}
+			err := library.Add(tt.example)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Add() error = %v, want %v", err, tt.err)
+			}
+			var want []Example
+			if tt.err == nil {
+				want = []Example{tt.want}
+			}
+			if !reflect.DeepEqual(library.Examples, want) {
+				t.Errorf("Examples = %+v, want %+v", library.Examples, want)
+			}
+		})
+	}
+}
+
+func TestLibrary_Select(t *testing.T) {
+	library := &Library{Examples: []Example{
+		{Query: "list files", Command: "ls"},
+		{Query: "list all hidden files", Command: "ls -a"},
+		{Query: "count lines in files", Command: "wc -l *"},
+		{Query: "show disk usage", Command: "df -h"},
+		{Query: "list files twice", Command: "ls\nls"},
+	}}
+	tests := []struct {
+		name  string
+		query string
+		n     int
+		want  []string
+	}{
+		{name: "most relevant first", query: "list files", n: 3, want: []string{"ls", "ls -a", "wc -l *"}},
+		{name: "count limit", query: "list files", n: 1, want: []string{"ls"}},
+		{name: "equal scores keep the library order", query: "hidden lines", n: 3, want: []string{"ls -a", "wc -l *"}},
+		{name: "no common keywords", query: "restart nginx", n: 3},
+		{name: "case and punctuation", query: "Disk usage?", n: 3, want: []string{"df -h"}},
+		{name: "zero count", query: "list files", n: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, example := range library.Select(tt.query, tt.n) {
+				got = append(got, example.Command)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLibrary_SaveAndLoad(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	library, err := Load(fs, "/aai/examples.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = library.Add(Example{Query: "list files", Command: "ls"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = library.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(fs, "/aai/examples.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Examples, library.Examples) {
+		t.Errorf("loaded Examples = %+v, want %+v", loaded.Examples, library.Examples)
+	}
+	if _, err = loaded.Remove(1); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("Remove() error = %v, want %v", err, ErrIndexOutOfRange)
+	}
+}
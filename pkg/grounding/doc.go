@@ -0,0 +1,257 @@
+package grounding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// lookupTimeout is the maximum time of a single man or --help lookup.
+	lookupTimeout = 2 * time.Second
+)
+
+var (
+	// ErrNoDoc is returned when a program has no local documentation.
+	ErrNoDoc = errors.New("no documentation found")
+
+	// overstrikeRegexp matches the overstrike sequences used by man for bold and underline.
+	overstrikeRegexp = regexp.MustCompile(".\x08")
+	// ansiRegexp matches ANSI escape sequences.
+	ansiRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// doc is a plain text documentation of a program.
+type doc struct {
+	// name describes the documentation, e.g. "man ls" or "ls --help".
+	name string
+	// man is true if the documentation is a man page.
+	man bool
+	// lines are the lines of the documentation.
+	lines []string
+}
+
+// lookupDoc returns the man page of the program or, if help is true and there is none, its --help output.
+// For programs with subcommands, the man page of the subcommand is preferred, e.g. git-commit.
+// Only programs with bare names are looked up, paths such as ./deploy.sh are never run.
+func lookupDoc(ctx context.Context, program, subcommand string, help bool) (*doc, error) {
+	if !isBareName(program) {
+		return nil, fmt.Errorf("%w for %s: not a program name", ErrNoDoc, program)
+	}
+	var pages []string
+	if subcommand != "" && isBareName(subcommand) {
+		pages = append(pages, program+"-"+subcommand)
+	}
+	pages = append(pages, program)
+
+	for _, page := range pages {
+		out, err := run(ctx, "man", page)
+		if err == nil {
+			return newDoc("man "+page, true, out), nil
+		}
+	}
+	if !help {
+		return nil, fmt.Errorf("%w for %s: no man page", ErrNoDoc, program)
+	}
+
+	// Only run programs that actually exist in PATH, the command may be a typo or an alias.
+	if _, err := exec.LookPath(program); err != nil {
+		return nil, fmt.Errorf("%w for %s: %v", ErrNoDoc, program, err)
+	}
+	out, err := run(ctx, program, "--help")
+	if err != nil {
+		return nil, fmt.Errorf("%w for %s: %v", ErrNoDoc, program, err)
+	}
+	return newDoc(program+" --help", false, out), nil
+}
+
+// isBareName returns true if the name is a program name that is looked up in PATH,
+// not a path and not an option that man would parse.
+func isBareName(name string) bool {
+	return name != "" && !strings.ContainsRune(name, '/') && !strings.HasPrefix(name, "-")
+}
+
+// run executes the documentation command and returns its output.
+// Output is returned even if the command fails, as some programs
+// exit with an error code after printing --help.
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "MANPAGER=cat", "PAGER=cat", "MANWIDTH=100", "GROFF_NO_SGR=1")
+	out, err := cmd.CombinedOutput()
+	if len(strings.TrimSpace(string(out))) == 0 {
+		if err == nil {
+			err = ErrNoDoc
+		}
+		return "", err
+	}
+	if err != nil && name == "man" {
+		// man prints "No manual entry" errors.
+		return "", err
+	}
+	return string(out), nil
+}
+
+func newDoc(name string, man bool, text string) *doc {
+	text = overstrikeRegexp.ReplaceAllString(text, "")
+	text = ansiRegexp.ReplaceAllString(text, "")
+	return &doc{
+		name:  name,
+		man:   man,
+		lines: strings.Split(text, "\n"),
+	}
+}
+
+// excerpts returns the program description and the documentation of the provided flags.
+func (d *doc) excerpts(flags []string) []Excerpt {
+	var excerpts []Excerpt
+	if description := d.description(); description != "" {
+		excerpts = append(excerpts, Excerpt{Source: d.source("NAME"), Text: description})
+	}
+
+	seen := make(map[int]bool)
+	for _, flag := range flags {
+		name := flagName(flag)
+		names := []string{name}
+		if !d.hasOption(name) && !strings.HasPrefix(flag, "--") && len(name) > 2 {
+			// Combined short flags, e.g. -xzf.
+			names = names[:0]
+			for _, r := range strings.TrimPrefix(name, "-") {
+				names = append(names, "-"+string(r))
+			}
+		}
+		for _, name := range names {
+			start, ok := d.findOption(name)
+			if !ok || seen[start] {
+				continue
+			}
+			seen[start] = true
+			excerpts = append(excerpts, Excerpt{
+				Source: d.source(d.sectionAt(start)),
+				Text:   d.entryAt(start),
+			})
+		}
+	}
+	return excerpts
+}
+
+// source returns the source description of the documentation section.
+func (d *doc) source(section string) string {
+	if !d.man || section == "" {
+		return d.name
+	}
+	return fmt.Sprintf("%s, %s", d.name, section)
+}
+
+// description returns the content of the NAME section of a man page,
+// or the first lines of --help output.
+func (d *doc) description() string {
+	if d.man {
+		for i, line := range d.lines {
+			if strings.TrimSpace(line) == "NAME" && i+1 < len(d.lines) {
+				return strings.TrimSpace(d.lines[i+1])
+			}
+		}
+		return ""
+	}
+
+	var lines []string
+	for _, line := range d.lines {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+		if len(lines) == 2 {
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d *doc) hasOption(name string) bool {
+	_, ok := d.findOption(name)
+	return ok
+}
+
+// findOption returns the index of the line that documents the option.
+func (d *doc) findOption(name string) (int, bool) {
+	for i, line := range d.lines {
+		for _, option := range optionNames(line) {
+			if option == name {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// entryAt returns the option entry starting at the line: the line itself
+// and the following lines that are indented deeper.
+func (d *doc) entryAt(start int) string {
+	entryIndent := indent(d.lines[start])
+	lines := []string{strings.TrimSpace(d.lines[start])}
+	for _, line := range d.lines[start+1:] {
+		if strings.TrimSpace(line) == "" || indent(line) <= entryIndent || len(lines) == maxEntryLines {
+			break
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sectionAt returns the name of the section that contains the line.
+func (d *doc) sectionAt(index int) string {
+	for i := index; i >= 0; i-- {
+		line := d.lines[i]
+		if line == "" || indent(line) > 0 {
+			continue
+		}
+		line = strings.TrimSuffix(strings.TrimSpace(line), ":")
+		if strings.ToUpper(line) == line {
+			return line
+		}
+	}
+	return ""
+}
+
+// optionNames returns the options documented by the line, e.g. "-a, --all" returns [-a --all].
+func optionNames(line string) []string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "-") {
+		return nil
+	}
+	// Option names are separated from the description by at least two spaces or a tab.
+	if i := strings.Index(line, "  "); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, "\t"); i >= 0 {
+		line = line[:i]
+	}
+
+	var names []string
+	for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		if strings.HasPrefix(field, "-") {
+			names = append(names, flagName(field))
+		}
+	}
+	return names
+}
+
+// flagName strips the value from a flag, e.g. --color=auto returns --color.
+func flagName(flag string) string {
+	if i := strings.IndexAny(flag, "=["); i >= 0 {
+		return flag[:i]
+	}
+	return flag
+}
+
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
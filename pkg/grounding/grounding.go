@@ -0,0 +1,101 @@
+// Package grounding looks up local documentation (man pages and --help output)
+// of the programs used in a command, so that prompts can be grounded in it.
+package grounding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxEntryLines is the maximum number of lines of a single excerpt.
+	maxEntryLines = 8
+	// maxExcerptsSize is the maximum total size of excerpts, in bytes.
+	maxExcerptsSize = 4 * 1024
+)
+
+// Excerpt is a fragment of a program documentation.
+type Excerpt struct {
+	// Source describes where the excerpt comes from, e.g. "man ls, OPTIONS".
+	Source string `json:"source"`
+	// Text is the excerpt itself.
+	Text string `json:"text"`
+}
+
+// usage is a program used in a command, with the flags passed to it.
+type usage struct {
+	program    string
+	subcommand string
+	flags      []string
+}
+
+// Lookup returns documentation excerpts for every program and flag used in the command.
+// Programs without local documentation are skipped. If help is true, programs without
+// a man page are run with --help.
+func Lookup(ctx context.Context, command string, help bool) ([]Excerpt, error) {
+	nodes, err := shell.Parse(command)
+	if err != nil {
+		return nil, err
+	}
+
+	var excerpts []Excerpt
+	size := 0
+	for _, u := range usages(nodes) {
+		doc, err := lookupDoc(ctx, u.program, u.subcommand, help)
+		if err != nil {
+			log.Debug().Err(err).Str("program", u.program).Msg("no documentation found")
+			continue
+		}
+		for _, excerpt := range doc.excerpts(u.flags) {
+			if size+len(excerpt.Text) > maxExcerptsSize {
+				log.Debug().Str("source", excerpt.Source).Msg("documentation excerpt skipped, size limit reached")
+				continue
+			}
+			size += len(excerpt.Text)
+			excerpts = append(excerpts, excerpt)
+		}
+	}
+	return excerpts, nil
+}
+
+// usages returns programs used in the parsed command, including command substitutions.
+func usages(nodes []*shell.Node) []usage {
+	var result []usage
+	shell.Walk(nodes, func(node *shell.Node, _ int) {
+		if node.Kind != shell.KindCommand {
+			return
+		}
+		// current is the index of the last program of the command.
+		current := -1
+		for _, child := range node.Children {
+			switch child.Kind {
+			case shell.KindProgram:
+				result = append(result, usage{program: child.Text})
+				current = len(result) - 1
+			case shell.KindSubcommand:
+				if current >= 0 {
+					result[current].subcommand = child.Text
+				}
+			case shell.KindFlag:
+				if current >= 0 {
+					result[current].flags = append(result[current].flags, child.Text)
+				}
+			}
+		}
+	})
+	return result
+}
+
+// String formats the excerpts as a prompt section.
+func String(excerpts []Excerpt) string {
+	var builder strings.Builder
+	for _, excerpt := range excerpts {
+		builder.WriteString(fmt.Sprintf("[%s]\n%s\n", excerpt.Source, excerpt.Text))
+	}
+	return builder.String()
+}
@@ -0,0 +1,128 @@
+package grounding
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+)
+
+const (
+	// manLs is a man page fixture, the NAME heading is bold with overstrike sequences.
+	manLs = "LS(1)                       User Commands                      LS(1)\n" +
+		"\n" +
+		"N\bNA\bAM\bME\bE\n" +
+		"       ls - list directory contents\n" +
+		"\n" +
+		"DESCRIPTION\n" +
+		"       List information about the FILEs.\n" +
+		"\n" +
+		"       -a, --all\n" +
+		"              do not ignore entries starting with .\n" +
+		"\n" +
+		"       -l     use a long listing format\n" +
+		"\n" +
+		"       --color[=WHEN]\n" +
+		"              colorize the output; WHEN can be 'always', 'auto', or 'never'\n" +
+		"\n" +
+		"       -w, --width=COLS\n" +
+		"              set output width to COLS\n"
+
+	// helpTool is a --help output fixture.
+	helpTool = "Usage: tool [OPTIONS] FILE\n" +
+		"Process the FILE.\n" +
+		"\n" +
+		"Options:\n" +
+		"  -v, --verbose   print more\n" +
+		"  -n NUM          repeat NUM times\n" +
+		"  -o FILE\twrite to FILE\n"
+)
+
+func TestDoc_Excerpts(t *testing.T) {
+	man := newDoc("man ls", true, manLs)
+	help := newDoc("tool --help", false, helpTool)
+	description := Excerpt{Source: "man ls, NAME", Text: "ls - list directory contents"}
+	all := Excerpt{Source: "man ls, DESCRIPTION", Text: "-a, --all\ndo not ignore entries starting with ."}
+	long := Excerpt{Source: "man ls, DESCRIPTION", Text: "-l     use a long listing format"}
+
+	tests := []struct {
+		name  string
+		doc   *doc
+		flags []string
+		want  []Excerpt
+	}{
+		{name: "description only", doc: man, want: []Excerpt{description}},
+		{name: "short flag", doc: man, flags: []string{"-l"}, want: []Excerpt{description, long}},
+		{name: "combined short flags", doc: man, flags: []string{"-la"}, want: []Excerpt{description, long, all}},
+		{name: "same option twice", doc: man, flags: []string{"-a", "--all"}, want: []Excerpt{description, all}},
+		{name: "long flag with value", doc: man, flags: []string{"--color=auto"}, want: []Excerpt{description, {Source: "man ls, DESCRIPTION", Text: "--color[=WHEN]\ncolorize the output; WHEN can be 'always', 'auto', or 'never'"}}},
+		{name: "undocumented flag", doc: man, flags: []string{"--nope", "-z"}, want: []Excerpt{description}},
+		{
+			name:  "help output",
+			doc:   help,
+			flags: []string{"--verbose", "-n"},
+			want: []Excerpt{
+				{Source: "tool --help", Text: "Usage: tool [OPTIONS] FILE\nProcess the FILE."},
+				{Source: "tool --help", Text: "-v, --verbose   print more"},
+				{Source: "tool --help", Text: "-n NUM          repeat NUM times"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.doc.excerpts(tt.flags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("excerpts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionNames(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "       -a, --all", want: []string{"-a", "--all"}},
+		{line: "       -l     use a long listing format", want: []string{"-l"}},
+		{line: "       --color[=WHEN]", want: []string{"--color"}},
+		{line: "       -w, --width=COLS", want: []string{"-w", "--width"}},
+		{line: "  -n NUM          repeat NUM times", want: []string{"-n"}},
+		{line: "  -o FILE\twrite to FILE", want: []string{"-o"}},
+		{line: "  -v, --verbose   print more -x", want: []string{"-v", "--verbose"}},
+		{line: "       List information about the FILEs."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := optionNames(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("optionNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupDoc_NotProgramName(t *testing.T) {
+	// Paths and options are never run or passed to man.
+	for _, program := range []string{"./deploy.sh", "/usr/bin/ls", "-k", ""} {
+		if _, err := lookupDoc(context.Background(), program, "", true); !errors.Is(err, ErrNoDoc) {
+			t.Errorf("lookupDoc(%q) error = %v, want %v", program, err, ErrNoDoc)
+		}
+	}
+}
+
+func TestUsages(t *testing.T) {
+	nodes, err := shell.Parse(`sudo git commit -am "$(date +%s)" | grep -v -e x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []usage{
+		{program: "sudo"},
+		{program: "git", subcommand: "commit", flags: []string{"-am"}},
+		{program: "date"},
+		{program: "grep", flags: []string{"-v", "-e"}},
+	}
+	if got := usages(nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("usages() = %+v, want %+v", got, want)
+	}
+}
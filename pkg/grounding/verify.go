@@ -0,0 +1,86 @@
+package grounding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/rs/zerolog/log"
+)
+
+// UnknownFlag is a flag that is not documented by the program it is passed to.
+type UnknownFlag struct {
+	// Program is the program the flag is passed to.
+	Program string `json:"program"`
+	// Flag is the unknown flag.
+	Flag string `json:"flag"`
+	// Source is the documentation that was checked, e.g. "man ls".
+	Source string `json:"source"`
+}
+
+func (u UnknownFlag) String() string {
+	return fmt.Sprintf("%s: unknown flag %s (not found in %s)", u.Program, u.Flag, u.Source)
+}
+
+// Verify checks every flag in the command against the local documentation
+// of its program and returns the flags that are not documented.
+// Programs without local documentation are not verified. If help is true, programs without
+// a man page are run with --help.
+func Verify(ctx context.Context, command string, help bool) ([]UnknownFlag, error) {
+	nodes, err := shell.Parse(command)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []UnknownFlag
+	for _, u := range usages(nodes) {
+		if len(u.flags) == 0 {
+			continue
+		}
+		doc, err := lookupDoc(ctx, u.program, u.subcommand, help)
+		if err != nil {
+			log.Debug().Err(err).Str("program", u.program).Msg("cannot verify flags")
+			continue
+		}
+		for _, flag := range u.flags {
+			if !doc.documents(flag) {
+				unknown = append(unknown, UnknownFlag{Program: u.program, Flag: flag, Source: doc.name})
+			}
+		}
+	}
+	return unknown, nil
+}
+
+// documents returns true if the flag is documented. Short flags can be
+// combined (-xzf) or have an attached value (-n5, -i:8080).
+// Numeric flags, such as -1, are not verified.
+func (d *doc) documents(flag string) bool {
+	name := flagName(flag)
+	if d.hasOption(name) {
+		return true
+	}
+	letters := strings.TrimPrefix(name, "-")
+	if strings.IndexFunc(letters, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		// Negative numbers are usually values, e.g. "find -mtime -1".
+		return true
+	}
+	if strings.HasPrefix(name, "--") || len(name) <= 2 {
+		return false
+	}
+	if !d.hasOption(name[:2]) {
+		return false
+	}
+	if strings.IndexFunc(letters, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+		// The first letter is a flag with an attached value.
+		return true
+	}
+	for _, r := range letters[1:] {
+		if !d.hasOption("-" + string(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package grounding
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDoc_Documents(t *testing.T) {
+	man := newDoc("man ls", true, manLs)
+	help := newDoc("tool --help", false, helpTool)
+	tests := []struct {
+		doc  *doc
+		flag string
+		want bool
+	}{
+		{doc: man, flag: "-l", want: true},
+		{doc: man, flag: "--all", want: true},
+		{doc: man, flag: "-la", want: true},
+		{doc: man, flag: "-al", want: true},
+		{doc: man, flag: "-lz"},
+		{doc: man, flag: "-z"},
+		{doc: man, flag: "--color=auto", want: true},
+		{doc: man, flag: "--width=80", want: true},
+		{doc: man, flag: "--colour"},
+		{doc: man, flag: "--colour=auto"},
+		{doc: man, flag: "-w80", want: true},
+		{doc: man, flag: "-1", want: true},
+		{doc: help, flag: "-n5", want: true},
+		{doc: help, flag: "-vo", want: true},
+		{doc: help, flag: "--verbose", want: true},
+		{doc: help, flag: "--quiet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.doc.name+" "+tt.flag, func(t *testing.T) {
+			if got := tt.doc.documents(tt.flag); got != tt.want {
+				t.Errorf("documents(%q) = %v, want %v", tt.flag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify_NoDoc(t *testing.T) {
+	// Programs without documentation, such as scripts, are not verified.
+	unknown, err := Verify(context.Background(), "./deploy.sh --bogus", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unknown) != 0 {
+		t.Errorf("Verify() = %v, want no unknown flags", unknown)
+	}
+	if _, err = Verify(context.Background(), "ls |", true); err == nil {
+		t.Error("Verify() error = nil, want a parse error")
+	}
+}
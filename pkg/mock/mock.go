@@ -0,0 +1,235 @@
+// Package mock is a provider that answers from a yaml rules file instead of an AI model.
+// It does not need network access or an API key, so it is used in demos and end-to-end tests.
+//
+// Example of a rules file:
+//
+//	latency: 200ms
+//	rules:
+//	  - query: 'open ports'
+//	    command: 'lsof -i -P -n | grep LISTEN'
+//	  - query: '^ls -l$'
+//	    explanation: 'List the files in long format'
+//	    parts: {ls: 'List directory contents', -l: 'Use a long listing format'}
+//	  - query: 'flaky'
+//	    error: {status: 503, message: 'overloaded', times: 1}
+//	    command: 'echo ok'
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrNoRule is returned when no rule answers the query.
+	ErrNoRule = errors.New("no matching mock rule")
+	// ErrInvalidRules is returned when the rules file has invalid rules.
+	ErrInvalidRules = errors.New("invalid mock rules")
+)
+
+type Config struct {
+	// Rules is the path of the rules file.
+	Rules string `config:"providers.mock.rules"`
+	// Latency is the default latency of the answers.
+	Latency time.Duration `config:"providers.mock.latency"`
+}
+
+// Rules is a list of rules stored in a yaml file.
+type Rules struct {
+	// Latency is the default latency of the answers, unless the latency is configured.
+	Latency time.Duration `yaml:"latency,omitempty"`
+	Rules   []Rule        `yaml:"rules"`
+}
+
+// Rule answers the queries that match its regular expression. Suggestions, translations
+// and scripts are answered with the command, explanations with the explanation
+// and part explanations with the parts.
+type Rule struct {
+	// Query is a regular expression matched against the query or the explained command.
+	Query       string `yaml:"query"`
+	Command     string `yaml:"command,omitempty"`
+	Explanation string `yaml:"explanation,omitempty"`
+	// Parts are the explanations of command parts, by part.
+	Parts map[string]string `yaml:"parts,omitempty"`
+	// Latency delays the answer, it overrides the latency of the rules file.
+	Latency time.Duration `yaml:"latency,omitempty"`
+	// Error is returned instead of the answer.
+	Error *InjectedError `yaml:"error,omitempty"`
+
+	// regexp is the compiled query.
+	regexp *regexp.Regexp
+}
+
+// InjectedError is an error returned by a rule, e.g. to test retries and fallbacks.
+type InjectedError struct {
+	// Status is the HTTP status code of the error, rate limits (429) and server errors (5xx) are retryable.
+	Status  int    `yaml:"status,omitempty"`
+	Message string `yaml:"message,omitempty"`
+	// Times is the number of calls that fail before the rule answers, 0 means that every call fails.
+	Times int `yaml:"times,omitempty"`
+}
+
+func (e *InjectedError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = "injected error"
+	}
+	if e.Status == 0 {
+		return message
+	}
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.Status, message)
+}
+
+// Retryable returns true for rate limits and server errors, like the errors of real providers.
+func (e *InjectedError) Retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= http.StatusInternalServerError
+}
+
+// Load reads the rules from the provided path and compiles their queries.
+func Load(fs afero.Fs, path string) (*Rules, error) {
+	path = os.ExpandEnv(path)
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock rules %s: %w", path, err)
+	}
+	var rules Rules
+	if err = yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse mock rules %s: %w", path, err)
+	}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if rule.regexp, err = regexp.Compile(rule.Query); err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", ErrInvalidRules, i+1, err)
+		}
+	}
+	return &rules, nil
+}
+
+// Provider answers from the rules.
+type Provider struct {
+	rules *Rules
+	// latency is the default latency of the answers.
+	latency time.Duration
+	// ctx cancels the delayed answers.
+	ctx context.Context
+
+	// mu guards failures.
+	mu sync.Mutex
+	// failures are the number of injected errors returned by every rule, by index.
+	failures map[int]int
+}
+
+// New creates a provider that answers from the rules. The answers are delayed by the latency,
+// unless a rule sets its own, and canceled with the context.
+func New(ctx context.Context, rules *Rules, latency time.Duration) *Provider {
+	return &Provider{rules: rules, latency: latency, ctx: ctx, failures: make(map[int]int)}
+}
+
+// Suggest answers with the command of the first rule that matches the query.
+func (p *Provider) Suggest(query string) (string, error) {
+	rule, err := p.answer(query, func(rule *Rule) bool { return rule.Command != "" })
+	if err != nil {
+		return "", err
+	}
+	return rule.Command, nil
+}
+
+// Explain answers with the explanation of the first rule that matches the command.
+func (p *Provider) Explain(command string) (string, error) {
+	rule, err := p.answer(command, func(rule *Rule) bool { return rule.Explanation != "" })
+	if err != nil {
+		return "", err
+	}
+	return rule.Explanation, nil
+}
+
+// ExplainParts answers with the part explanations of the first rule that matches the command.
+// Parts without an explanation have empty explanations.
+func (p *Provider) ExplainParts(command string, parts []string) ([]string, error) {
+	rule, err := p.answer(command, func(rule *Rule) bool { return len(rule.Parts) > 0 })
+	if err != nil {
+		return nil, err
+	}
+	explanations := make([]string, len(parts))
+	for i, part := range parts {
+		explanations[i] = rule.Parts[part]
+	}
+	return explanations, nil
+}
+
+// Translate answers with the command of the first rule that matches the translated command.
+func (p *Provider) Translate(command string, from, to shell.Dialect) (string, error) {
+	return p.Suggest(command)
+}
+
+// Script answers with the command of the first rule that matches the task.
+func (p *Provider) Script(task string) (string, error) {
+	return p.Suggest(task)
+}
+
+// answer returns the first rule that matches the text and has an answer, after its latency.
+// It returns the injected error of the rule instead, if the rule has not failed enough times yet.
+func (p *Provider) answer(text string, answers func(rule *Rule) bool) (*Rule, error) {
+	for i := range p.rules.Rules {
+		rule := &p.rules.Rules[i]
+		if !rule.regexp.MatchString(text) || (!answers(rule) && rule.Error == nil) {
+			continue
+		}
+		if err := p.wait(rule); err != nil {
+			return nil, err
+		}
+		if p.fail(i) {
+			return nil, rule.Error
+		}
+		if !answers(rule) {
+			continue
+		}
+		return rule, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNoRule, text)
+}
+
+// fail returns true if the rule returns its injected error, and counts the failure.
+func (p *Provider) fail(index int) bool {
+	injected := p.rules.Rules[index].Error
+	if injected == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if injected.Times > 0 && p.failures[index] >= injected.Times {
+		return false
+	}
+	p.failures[index]++
+	return true
+}
+
+// wait waits for the latency of the rule, or until the context is canceled.
+func (p *Provider) wait(rule *Rule) error {
+	latency := p.latency
+	if rule.Latency > 0 {
+		latency = rule.Latency
+	}
+	if latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
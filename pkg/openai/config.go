@@ -1,18 +1,26 @@
 package openai
 
+import "time"
+
 // RequestBase will be used in the request body.
 type RequestBase struct {
-	Model            string  `json:"model" config:"openai.model"`
-	Temperature      float64 `json:"temperature" config:"openai.temperature"`
-	MaxTokens        int     `json:"max_tokens" config:"openai.maxtokens"`
-	TopP             float64 `json:"top_p" config:"openai.topp"`
-	FrequencyPenalty float64 `json:"frequency_penalty" config:"openai.frequencypenalty"`
-	PresencePenalty  float64 `json:"presence_penalty" config:"openai.presencepenalty"`
+	Model            string  `json:"model" config:"providers.openai.model"`
+	Temperature      float64 `json:"temperature" config:"providers.openai.temperature"`
+	MaxTokens        int     `json:"max_tokens" config:"providers.openai.maxtokens"`
+	TopP             float64 `json:"top_p" config:"providers.openai.topp"`
+	FrequencyPenalty float64 `json:"frequency_penalty" config:"providers.openai.frequencypenalty"`
+	PresencePenalty  float64 `json:"presence_penalty" config:"providers.openai.presencepenalty"`
 }
 
 type Config struct {
 	// ApiKey is the OpenAI API key.
-	ApiKey string `config:"openai.apikey"`
+	ApiKey string `config:"providers.openai.apikey"`
+	// BaseUrl is the base URL of the API, e.g. a local OpenAI compatible server.
+	BaseUrl string `config:"providers.openai.baseurl"`
+	// ScriptMaxTokens is the max tokens of script generation requests.
+	ScriptMaxTokens int `config:"script.maxtokens"`
+	// Timeout is the timeout of a single request.
+	Timeout time.Duration `config:"providers.openai.timeout"`
 	// OpenAI request configuration
 	RequestBase
 }
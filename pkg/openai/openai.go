@@ -2,60 +2,231 @@ package openai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/examples"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/grounding"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/redact"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/secrets"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
 
 	"github.com/rs/zerolog/log"
 )
 
 const (
-	// apiUrl is the URL of the OpenAI API.
-	apiUrl = "https://api.openai.com/v1/completions"
+	// DefaultBaseUrl is the base URL of the OpenAI API.
+	DefaultBaseUrl = "https://api.openai.com/v1"
+	// completionsPath is the path of the completions endpoint.
+	completionsPath = "/completions"
+	// modelsPath is the path of the models endpoint.
+	modelsPath = "/models/"
 
 	// queryPrefixSequence is a sequence of tokens that is used to prefix the query.
 	// it is also used as stop sequence to terminate the completion.
 	queryPrefixSequence = "query"
+	// taskPrefixSequence is a sequence of tokens that is used to prefix the script task.
+	// Scripts use it as the stop sequence, since they can contain the word "query".
+	taskPrefixSequence = "task:"
+	// scriptShebang is the first line of generated scripts.
+	scriptShebang = "#!/usr/bin/env bash"
+
+	// partMaxTokens is the minimal token budget for explanation of a single command part.
+	partMaxTokens = 30
+)
+
+var (
+	// ErrModelNotFound is returned when the model is not available.
+	ErrModelNotFound = errors.New("model not found")
+	// ErrUnauthorized is returned when the API key is invalid.
+	ErrUnauthorized = errors.New("invalid api key")
+
+	// translateExamples is the same script written in every supported dialect.
+	// It is used as the translation prompt example.
+	translateExamples = map[shell.Dialect]string{
+		shell.Bash:       `export FOO=bar && for f in *.txt; do echo "$f"; done`,
+		shell.Posix:      `export FOO=bar && for f in *.txt; do echo "$f"; done`,
+		shell.Zsh:        `export FOO=bar && for f in *.txt; do echo "$f"; done`,
+		shell.Fish:       `set -gx FOO bar; and for f in *.txt; echo $f; end`,
+		shell.PowerShell: `$env:FOO = "bar"; if ($?) { foreach ($f in Get-ChildItem *.txt) { Write-Output $f.Name } }`,
+	}
+
+	// partExplanationRegexp matches a numbered explanation line, e.g. "2. list files".
+	partExplanationRegexp = regexp.MustCompile(`^\s*(\d+)[.):]\s*(.*)$`)
 )
 
 type Client struct {
 	Config Config
+
+	// examples are the few-shot examples used in suggestion prompts.
+	examples []examples.Example
+	// context is additional data attached to prompts, such as a command output.
+	context string
+	// grounding are documentation excerpts that explanations should be based on.
+	grounding []grounding.Excerpt
+	// redaction redacts prompts before they are sent and restores the responses.
+	redaction *redact.Pipeline
+	// httpClient sends the requests.
+	httpClient *http.Client
+	// ctx cancels the requests.
+	ctx context.Context
+
+	// mu guards usage.
+	mu sync.Mutex
+	// usage is the total usage of the completion requests.
+	usage Usage
+}
+
+// Option configures optional Client settings.
+type Option func(*Client)
+
+// WithExamples sets the few-shot examples used in suggestion prompts.
+// If no examples are provided, examples.Default is used.
+func WithExamples(ex []examples.Example) Option {
+	return func(c *Client) {
+		c.examples = ex
+	}
+}
+
+// WithContext attaches additional data, such as a command output
+// or an error log, to the prompts as context.
+func WithContext(context string) Option {
+	return func(c *Client) {
+		c.context = context
+	}
+}
+
+// WithGrounding attaches documentation excerpts to explanation prompts.
+// The explanations are asked to cite the excerpt sources.
+func WithGrounding(excerpts []grounding.Excerpt) Option {
+	return func(c *Client) {
+		c.grounding = excerpts
+	}
+}
+
+// WithRedaction redacts every prompt with the pipeline before it is sent,
+// and restores the redacted values in the completions.
+func WithRedaction(pipeline *redact.Pipeline) Option {
+	return func(c *Client) {
+		c.redaction = pipeline
+	}
+}
+
+// WithRequestContext sends the requests with the context, so that they are canceled with it.
+func WithRequestContext(ctx context.Context) Option {
+	return func(c *Client) {
+		c.ctx = ctx
+	}
+}
+
+// WithTransport sends the requests with the transport, e.g. a cassette in tests.
+// If it is nil, http.DefaultTransport is used.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = transport
+	}
 }
 
 // NewClient creates a new OpenAI client.
-func NewClient(config Config) *Client {
-	return &Client{
-		Config: config,
+func NewClient(config Config, options ...Option) *Client {
+	client := &Client{
+		Config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		ctx:        context.Background(),
+	}
+	for _, option := range options {
+		option(client)
 	}
+	return client
 }
 
 // Suggest suggests a command for a given query.
 func (c *Client) Suggest(query string) (string, error) {
-	prompt := suggestPrompt(query)
-	return c.doRequest(prompt)
+	prompt := suggestPrompt(query, c.examples, c.context)
+	return c.doRequest(c.Config.RequestBase, prompt, queryPrefixSequence)
 }
 
 // Explain explains a command.
 func (c *Client) Explain(command string) (string, error) {
-	prompt := explainPrompt(command)
-	return c.doRequest(prompt)
+	prompt := explainPrompt(command, c.context, c.grounding)
+	return c.doRequest(c.Config.RequestBase, prompt, queryPrefixSequence)
+}
+
+// Translate translates a command or a short script from one shell dialect to another.
+func (c *Client) Translate(command string, from, to shell.Dialect) (string, error) {
+	prompt := translatePrompt(command, from, to)
+	return c.doRequest(c.Config.RequestBase, prompt, queryPrefixSequence)
+}
+
+// Script generates a complete bash script for a given task.
+// It uses its own token budget, ScriptMaxTokens.
+func (c *Client) Script(task string) (string, error) {
+	base := c.Config.RequestBase
+	base.MaxTokens = c.Config.ScriptMaxTokens
+
+	prompt := scriptPrompt(task, c.context)
+	response, err := c.doRequest(base, prompt, taskPrefixSequence)
+	if err != nil {
+		return "", err
+	}
+	return scriptShebang + "\n" + response, nil
+}
+
+// ExplainParts explains every part of a command. It returns one explanation
+// per part, in the same order. Parts that were not explained have empty explanations.
+// The token budget is increased if it is too small for the number of parts.
+func (c *Client) ExplainParts(command string, parts []string) ([]string, error) {
+	base := c.Config.RequestBase
+	if minTokens := partMaxTokens * len(parts); base.MaxTokens < minTokens {
+		base.MaxTokens = minTokens
+	}
+
+	prompt := explainPartsPrompt(command, parts, c.context, c.grounding)
+	response, err := c.doRequest(base, prompt, queryPrefixSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	explanations := make([]string, len(parts))
+	for _, line := range strings.Split(response, "\n") {
+		match := partExplanationRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		index, err := strconv.Atoi(match[1])
+		if err != nil || index < 1 || index > len(parts) {
+			continue
+		}
+		explanations[index-1] = strings.TrimSpace(match[2])
+	}
+	return explanations, nil
 }
 
 // doRequest performs a request to the OpenAI API.
-func (c *Client) doRequest(prompt string) (string, error) {
+// The completion is terminated at the stop sequence.
+func (c *Client) doRequest(base RequestBase, prompt string, stop string) (string, error) {
+	if c.redaction != nil {
+		prompt = c.redaction.Redact(prompt)
+	}
 	reqBody := requestBody{
-		RequestBase: c.Config.RequestBase,
+		RequestBase: base,
 		Prompt:      prompt,
-		Stop:        []string{queryPrefixSequence},
+		Stop:        []string{stop},
 	}
 	jsonReqBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, apiUrl, bytes.NewReader(jsonReqBody))
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.url(completionsPath), bytes.NewReader(jsonReqBody))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -63,11 +234,15 @@ func (c *Client) doRequest(prompt string) (string, error) {
 	req.Header.Add("Content-Type", "application/json")
 
 	for k, v := range req.Header {
+		if k == "Authorization" {
+			// The header contains the api key, it is never logged.
+			v = []string{secrets.Masked}
+		}
 		log.Debug().Strs(k, v).Msg("request header")
 	}
 	log.Debug().Str("body", string(jsonReqBody)).Msg("request body")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
 	}
@@ -82,21 +257,88 @@ func (c *Client) doRequest(prompt string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
+	log.Debug().Msgf("response body: %s", resBody)
+	if res.StatusCode != http.StatusOK {
+		return "", &StatusError{StatusCode: res.StatusCode, Body: string(resBody)}
+	}
+
 	var completion responseBody
 	if err = json.Unmarshal(resBody, &completion); err != nil {
 		return "", fmt.Errorf("failed to unmarshal response (status code: %d): %w", res.StatusCode, err)
 	}
-
-	log.Debug().Msgf("response body: %s", resBody)
-	if res.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d, body: %s", res.StatusCode, resBody)
-	}
+	c.mu.Lock()
+	c.usage.add(completion.Usage)
+	c.mu.Unlock()
 
 	if len(completion.Choices) == 0 {
 		return "", fmt.Errorf("no completion found")
 	}
 
-	return strings.TrimSpace(completion.Choices[0].Text), nil
+	text := strings.TrimSpace(completion.Choices[0].Text)
+	if c.redaction != nil {
+		text = c.redaction.Restore(text)
+	}
+	return text, nil
+}
+
+// CheckModel checks that the configured model is available with the API key.
+func (c *Client) CheckModel() error {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.url(modelsPath+c.Config.Model), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.Config.ApiKey))
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to close response body")
+		}
+	}(res.Body)
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrModelNotFound, c.Config.Model)
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	}
+	return &StatusError{StatusCode: res.StatusCode}
+}
+
+// StatusError is returned when the API responds with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// Retryable returns true for rate limits and server errors, which may succeed on a later request.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// Usage returns the total usage of the completion requests sent by the client.
+func (c *Client) Usage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// url returns the URL of the API endpoint.
+func (c *Client) url(path string) string {
+	return strings.TrimSuffix(c.Config.BaseUrl, "/") + path
 }
 
 // requestBody is the request body of a completion request.
@@ -118,11 +360,7 @@ type responseBody struct {
 		Logprobs     interface{} `json:"logprobs"`
 		FinishReason string      `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage Usage `json:"usage"`
 	Error struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
@@ -133,19 +371,30 @@ type responseBody struct {
 
 /*
 suggestPrompt creates a prompt for a completion request.
-Example of a prompt with a query "show current directory":
+Example of a prompt with a query "show current directory",
+no user examples and no context:
 
 	query: create foo directory:
 	answer: mkdir foo
 	query: show current directory:
 	answer:
 */
-func suggestPrompt(query string) string {
+func suggestPrompt(query string, ex []examples.Example, context string) string {
 	var builder strings.Builder
-	// Prompt example:
-	builder.WriteString("query: create foo directory\n")
-	builder.WriteString("answer: mkdir foo\n")
+	if len(ex) == 0 {
+		ex = []examples.Example{examples.Default}
+	}
+	// Prompt examples:
+	for _, example := range ex {
+		builder.WriteString("query: ")
+		builder.WriteString(example.Query)
+		builder.WriteString("\n")
+		builder.WriteString("answer: ")
+		builder.WriteString(example.Command)
+		builder.WriteString("\n")
+	}
 	// Actual prompt:
+	writeContext(&builder, context)
 	builder.WriteString("query: ")
 	builder.WriteString(query)
 	builder.WriteString("\n")
@@ -155,12 +404,14 @@ func suggestPrompt(query string) string {
 }
 
 // explainPrompt creates a prompt for an explanation request.
-func explainPrompt(command string) string {
+func explainPrompt(command string, context string, excerpts []grounding.Excerpt) string {
 	var builder strings.Builder
 	// Prompt example:
 	builder.WriteString("query: cd $HOME\n")
 	builder.WriteString("answer:\nChange the current directory to the home directory\n")
 	//	Actual command:
+	writeContext(&builder, context)
+	writeGrounding(&builder, excerpts)
 	builder.WriteString("query: ")
 	builder.WriteString(command)
 	builder.WriteString("\n")
@@ -168,3 +419,120 @@ func explainPrompt(command string) string {
 
 	return builder.String()
 }
+
+// writeContext writes the context section of a prompt, if there is any context.
+func writeContext(builder *strings.Builder, context string) {
+	if context == "" {
+		return
+	}
+	builder.WriteString("context:\n")
+	builder.WriteString(context)
+	builder.WriteString("\n")
+}
+
+/*
+explainPartsPrompt creates a prompt for an explanation of every part of a command.
+Example of a prompt with a command "ls -l":
+
+	query: tar -xzf archive.tgz
+	parts:
+	1. tar
+	2. -xzf
+	3. archive.tgz
+	answer:
+	1. Archiving utility
+	2. Extract (-x) files from a gzip compressed (-z) archive file (-f)
+	3. The archive file to extract
+	query: ls -l
+	parts:
+	1. ls
+	2. -l
+	answer:
+*/
+func explainPartsPrompt(command string, parts []string, context string, excerpts []grounding.Excerpt) string {
+	var builder strings.Builder
+	// Prompt example:
+	builder.WriteString("query: tar -xzf archive.tgz\n")
+	builder.WriteString("parts:\n1. tar\n2. -xzf\n3. archive.tgz\n")
+	builder.WriteString("answer:\n")
+	builder.WriteString("1. Archiving utility\n")
+	builder.WriteString("2. Extract (-x) files from a gzip compressed (-z) archive file (-f)\n")
+	builder.WriteString("3. The archive file to extract\n")
+	// Actual command:
+	writeContext(&builder, context)
+	writeGrounding(&builder, excerpts)
+	builder.WriteString("query: ")
+	builder.WriteString(command)
+	builder.WriteString("\n")
+	builder.WriteString("parts:\n")
+	for i, part := range parts {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, part))
+	}
+	builder.WriteString("answer:\n")
+
+	return builder.String()
+}
+
+// writeGrounding writes the documentation section of a prompt, if there are any excerpts.
+func writeGrounding(builder *strings.Builder, excerpts []grounding.Excerpt) {
+	if len(excerpts) == 0 {
+		return
+	}
+	builder.WriteString("documentation:\n")
+	builder.WriteString(grounding.String(excerpts))
+	builder.WriteString("Use the documentation above and cite the source in brackets after each claim, e.g. [")
+	builder.WriteString(excerpts[0].Source)
+	builder.WriteString("].\n")
+}
+
+/*
+translatePrompt creates a prompt for a translation request.
+Example of a prompt with a command "export A=1" translated from bash to fish:
+
+	translate from bash to fish
+	query: export FOO=bar && for f in *.txt; do echo "$f"; done
+	answer:
+	set -gx FOO bar; and for f in *.txt; echo $f; end
+	query: export A=1
+	answer:
+
+The header is written once, so that the example answer is followed by the stop sequence.
+Otherwise, the model would continue its answer with the header line.
+*/
+func translatePrompt(command string, from, to shell.Dialect) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("translate from %s to %s\n", from, to))
+	// Prompt example:
+	builder.WriteString("query: ")
+	builder.WriteString(translateExamples[from])
+	builder.WriteString("\n")
+	builder.WriteString("answer:\n")
+	builder.WriteString(translateExamples[to])
+	builder.WriteString("\n")
+	// Actual command:
+	builder.WriteString("query: ")
+	builder.WriteString(command)
+	builder.WriteString("\n")
+	builder.WriteString("answer:\n")
+
+	return builder.String()
+}
+
+// scriptPrompt creates a prompt for a script generation request.
+// The prompt ends with the script shebang, so the completion is the script body.
+func scriptPrompt(task string, context string) string {
+	var builder strings.Builder
+	builder.WriteString("Write a complete bash script for the task. ")
+	builder.WriteString("Start with \"set -euo pipefail\", parse and validate the arguments, ")
+	builder.WriteString("print usage on invalid arguments and comment every step.\n")
+	writeContext(&builder, context)
+	builder.WriteString(taskPrefixSequence)
+	builder.WriteString(" ")
+	builder.WriteString(task)
+	builder.WriteString("\n")
+	builder.WriteString("script:\n")
+	builder.WriteString(scriptShebang)
+	builder.WriteString("\n")
+
+	return builder.String()
+}
@@ -0,0 +1,323 @@
+package openai
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/cassette"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/examples"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/grounding"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/redact"
+	"github.com/TomaszDomagala/ask-ai-cli/pkg/shell"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// recordBaseUrlEnv is the base URL of the server used to record the cassettes,
+	// the cassettes are recorded against the standIn server by default.
+	recordBaseUrlEnv = "AAI_RECORD_BASEURL"
+	// replayBaseUrl is the base URL of the replayed cassettes.
+	// The cassettes match requests by path, so the host is not important.
+	replayBaseUrl = "http://127.0.0.1:8080/v1"
+	// testApiKey is the API key sent to the stand-in server. It is scrubbed from the cassettes.
+	testApiKey = "sk-test"
+)
+
+// newTestClient creates a client that replays the cassette of the test from testdata,
+// or records it with AAI_RECORD=1. The cassette is not saved if the test fails.
+func newTestClient(t *testing.T, configure func(config *Config), options ...Option) *Client {
+	t.Helper()
+	baseUrl := replayBaseUrl
+	if cassette.Recording() {
+		baseUrl = os.Getenv(recordBaseUrlEnv)
+		if baseUrl == "" {
+			server := httptest.NewServer(standIn{})
+			t.Cleanup(server.Close)
+			baseUrl = server.URL + "/v1"
+		}
+	}
+	config := Config{
+		ApiKey:          testApiKey,
+		BaseUrl:         baseUrl,
+		ScriptMaxTokens: 500,
+		Timeout:         10 * time.Second,
+		RequestBase: RequestBase{
+			Model:       "text-davinci-003",
+			Temperature: 0,
+			MaxTokens:   100,
+			TopP:        1,
+		},
+	}
+	if configure != nil {
+		configure(&config)
+	}
+
+	path := filepath.Join("testdata", strings.ReplaceAll(t.Name(), "/", "_")+".yaml")
+	c, err := cassette.Open(afero.NewOsFs(), path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Scrub(config.ApiKey)
+	t.Cleanup(func() {
+		if t.Failed() {
+			return
+		}
+		if err := c.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	return NewClient(config, append(options, WithTransport(c))...)
+}
+
+func TestClient_Suggest(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	suggestion, err := client.Suggest("list all files including hidden ones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suggestion != "ls -la" {
+		t.Errorf("Suggest() = %q, want %q", suggestion, "ls -la")
+	}
+	usage := client.Usage()
+	if usage.TotalTokens == 0 || usage.TotalTokens != usage.PromptTokens+usage.CompletionTokens {
+		t.Errorf("Usage() = %+v, want the tokens of the request", usage)
+	}
+}
+
+func TestClient_Explain(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	explanation, err := client.Explain("cd -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Change the current directory to the previous directory"
+	if explanation != want {
+		t.Errorf("Explain() = %q, want %q", explanation, want)
+	}
+}
+
+func TestClient_ExplainParts(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	explanations, err := client.ExplainParts("ls -l", []string{"ls", "-l"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"List directory contents", "Use a long listing format"}
+	if strings.Join(explanations, "|") != strings.Join(want, "|") {
+		t.Errorf("ExplainParts() = %q, want %q", explanations, want)
+	}
+}
+
+func TestClient_Translate(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	translation, err := client.Translate("export A=1", shell.Bash, shell.Fish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if translation != "set -gx A 1" {
+		t.Errorf("Translate() = %q, want %q", translation, "set -gx A 1")
+	}
+}
+
+func TestClient_Script(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	script, err := client.Script("back up the home directory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(script, scriptShebang+"\nset -euo pipefail\n") {
+		t.Errorf("Script() = %q, want a script starting with the shebang", script)
+	}
+}
+
+func TestClient_Redaction(t *testing.T) {
+	client := newTestClient(t, nil, WithRedaction(redact.NewPipeline(redact.Detectors...)))
+
+	// The cassette matches the request body, so the email must be redacted from the prompt
+	// and restored in the suggestion.
+	suggestion, err := client.Suggest("set git email to jane@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "git config --global user.email jane@example.com"
+	if suggestion != want {
+		t.Errorf("Suggest() = %q, want %q", suggestion, want)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		status    int
+		retryable bool
+	}{
+		{name: "rate limit", query: "fail with status 429", status: 429, retryable: true},
+		{name: "server error", query: "fail with status 503", status: 503, retryable: true},
+		{name: "bad request", query: "fail with status 400", status: 400, retryable: false},
+		{name: "invalid json", query: "answer with invalid json"},
+		{name: "no choices", query: "answer with no choices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, nil)
+
+			_, err := client.Suggest(tt.query)
+			if err == nil {
+				t.Fatal("Suggest() error = nil, want an error")
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				if tt.status != 0 {
+					t.Fatalf("Suggest() error = %v, want a status error", err)
+				}
+				return
+			}
+			if statusErr.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", statusErr.StatusCode, tt.status)
+			}
+			if statusErr.Retryable() != tt.retryable {
+				t.Errorf("Retryable() = %v, want %v", statusErr.Retryable(), tt.retryable)
+			}
+		})
+	}
+}
+
+func TestClient_CheckModel(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  string
+		apiKey string
+		err    error
+	}{
+		{name: "available", model: "text-davinci-003"},
+		{name: "not found", model: "no-such-model", err: ErrModelNotFound},
+		{name: "unauthorized", model: "gpt-3.5-turbo-instruct", apiKey: "sk-invalid", err: ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(config *Config) {
+				config.Model = tt.model
+				if tt.apiKey != "" {
+					config.ApiKey = tt.apiKey
+				}
+			})
+
+			err := client.CheckModel()
+			if !errors.Is(err, tt.err) {
+				t.Errorf("CheckModel() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSuggestPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		examples []examples.Example
+		context  string
+		want     string
+	}{
+		{
+			name: "default example",
+			want: "query: create foo directory\nanswer: mkdir foo\nquery: show current directory\nanswer: ",
+		},
+		{
+			name:     "user examples",
+			examples: []examples.Example{{Query: "list files", Command: "ls"}, {Query: "show date", Command: "date"}},
+			want:     "query: list files\nanswer: ls\nquery: show date\nanswer: date\nquery: show current directory\nanswer: ",
+		},
+		{
+			name:    "context",
+			context: "/home/user",
+			want:    "query: create foo directory\nanswer: mkdir foo\ncontext:\n/home/user\nquery: show current directory\nanswer: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestPrompt("show current directory", tt.examples, tt.context); got != tt.want {
+				t.Errorf("suggestPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainPrompt(t *testing.T) {
+	excerpts := []grounding.Excerpt{{Source: "ls(1)", Text: "-l use a long listing format"}}
+
+	got := explainPrompt("ls -l", "", excerpts)
+	if !strings.HasPrefix(got, "query: cd $HOME\nanswer:\nChange the current directory to the home directory\n") {
+		t.Errorf("explainPrompt() = %q, want the example first", got)
+	}
+	if !strings.Contains(got, "documentation:\n") || !strings.Contains(got, "[ls(1)]") {
+		t.Errorf("explainPrompt() = %q, want the documentation with the source", got)
+	}
+	if !strings.HasSuffix(got, "query: ls -l\nanswer:\n") {
+		t.Errorf("explainPrompt() = %q, want the command last", got)
+	}
+}
+
+func TestExplainPartsPrompt(t *testing.T) {
+	got := explainPartsPrompt("ls -l", []string{"ls", "-l"}, "", nil)
+	if !strings.HasSuffix(got, "query: ls -l\nparts:\n1. ls\n2. -l\nanswer:\n") {
+		t.Errorf("explainPartsPrompt() = %q, want numbered parts", got)
+	}
+	if strings.Contains(got, "documentation:") {
+		t.Errorf("explainPartsPrompt() = %q, want no documentation without excerpts", got)
+	}
+}
+
+func TestTranslatePrompt(t *testing.T) {
+	got := translatePrompt("export A=1", shell.Bash, shell.Fish)
+	want := "translate from bash to fish\n" +
+		"query: " + translateExamples[shell.Bash] + "\n" +
+		"answer:\n" + translateExamples[shell.Fish] + "\n" +
+		"query: export A=1\n" +
+		"answer:\n"
+	if got != want {
+		t.Errorf("translatePrompt() = %q, want %q", got, want)
+	}
+
+	// The model continues its answer like the example answer, which must end at the stop sequence.
+	for from := range translateExamples {
+		for to := range translateExamples {
+			prompt := translatePrompt("export A=1", from, to)
+			if !strings.Contains(prompt, "answer:\n"+translateExamples[to]+"\n"+queryPrefixSequence) {
+				t.Errorf("translatePrompt(%s, %s) = %q, want the example answer followed by %q", from, to, prompt, queryPrefixSequence)
+			}
+		}
+	}
+}
+
+func TestScriptPrompt(t *testing.T) {
+	got := scriptPrompt("back up the home directory", "")
+	if !strings.Contains(got, taskPrefixSequence+" back up the home directory\n") {
+		t.Errorf("scriptPrompt() = %q, want the task", got)
+	}
+	if !strings.HasSuffix(got, "script:\n"+scriptShebang+"\n") {
+		t.Errorf("scriptPrompt() = %q, want the shebang last", got)
+	}
+}
+
+func TestUsage_Cost(t *testing.T) {
+	usage := Usage{PromptTokens: 400, CompletionTokens: 100, TotalTokens: 500}
+
+	cost, ok := usage.Cost("text-davinci-003")
+	if !ok || cost != 0.01 {
+		t.Errorf("Cost() = %v, %v, want 0.01, true", cost, ok)
+	}
+	if _, ok = usage.Cost("unknown-model"); ok {
+		t.Error("Cost() of an unknown model is known")
+	}
+}
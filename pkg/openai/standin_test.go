@@ -0,0 +1,117 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// standInAnswers are the completions of the stand-in server, by query.
+	standInAnswers = map[string]string{
+		"list all files including hidden ones": "ls -la",
+		"cd -":                                 "Change the current directory to the previous directory",
+		"ls -l":                                "1. List directory contents\n2. Use a long listing format",
+		"export A=1":                           "set -gx A 1",
+		"back up the home directory":           "set -euo pipefail\n\n# Archive the home directory\ntar -czf \"backup.tgz\" \"$HOME\"",
+		"set git email to REDACTED_EMAIL_1":    "git config --global user.email REDACTED_EMAIL_1",
+	}
+
+	// standInQueryRegexp matches the query and task lines of a prompt.
+	standInQueryRegexp = regexp.MustCompile(`(?m)^(?:query|task):\s*(.*)$`)
+	// standInStatusRegexp matches queries that ask for an error status, e.g. "fail with status 429".
+	standInStatusRegexp = regexp.MustCompile(`status (\d+)`)
+)
+
+// standIn is a local stand-in of the OpenAI API that the cassettes are recorded against.
+// It answers the queries of the tests with standInAnswers, and queries such as "fail with status 429",
+// "answer with invalid json" and "answer with no choices" with the corresponding errors.
+// Models named "no-such-model" do not exist and the API key "sk-invalid" is unauthorized.
+type standIn struct{}
+
+func (standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "Bearer sk-invalid" {
+		writeStandIn(w, http.StatusUnauthorized, "application/json", standInError("Incorrect API key provided"))
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && strings.Contains(r.URL.Path, modelsPath):
+		model := path.Base(r.URL.Path)
+		if model == "no-such-model" {
+			writeStandIn(w, http.StatusNotFound, "application/json", standInError("The model does not exist"))
+			return
+		}
+		writeStandIn(w, http.StatusOK, "application/json", map[string]string{"id": model, "object": "model", "owned_by": "stand-in"})
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, completionsPath):
+		serveCompletion(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveCompletion answers a completion request.
+func serveCompletion(w http.ResponseWriter, r *http.Request) {
+	var req requestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeStandIn(w, http.StatusBadRequest, "application/json", standInError(err.Error()))
+		return
+	}
+	query := ""
+	if queries := standInQueryRegexp.FindAllStringSubmatch(req.Prompt, -1); len(queries) > 0 {
+		query = queries[len(queries)-1][1]
+	}
+
+	if match := standInStatusRegexp.FindStringSubmatch(query); match != nil {
+		status, _ := strconv.Atoi(match[1])
+		writeStandIn(w, status, "application/json", map[string]any{"error": map[string]string{"message": "stand-in error", "type": "server_error"}})
+		return
+	}
+	if strings.Contains(query, "invalid json") {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("<html>bad gateway</html>"))
+		return
+	}
+
+	choices := []map[string]any{}
+	completionTokens := 0
+	if !strings.Contains(query, "no choices") {
+		text, ok := standInAnswers[query]
+		if !ok {
+			text = "echo ok"
+		}
+		choices = append(choices, map[string]any{"text": " " + text, "index": 0, "logprobs": nil, "finish_reason": "stop"})
+		completionTokens = len(strings.Fields(text))
+	}
+	promptTokens := len(strings.Fields(req.Prompt))
+	res := map[string]any{
+		"id":      "cmpl-1",
+		"object":  "text_completion",
+		"created": 1700000000,
+		"model":   req.Model,
+		"choices": choices,
+		"usage":   map[string]int{"prompt_tokens": promptTokens, "completion_tokens": completionTokens, "total_tokens": promptTokens + completionTokens},
+	}
+	writeStandIn(w, http.StatusOK, "application/json", res)
+}
+
+// standInError returns an API error body.
+func standInError(message string) map[string]any {
+	return map[string]any{"error": map[string]string{"message": message, "type": "invalid_request_error"}}
+}
+
+// writeStandIn writes the response body as JSON.
+func writeStandIn(w http.ResponseWriter, status int, contentType string, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to marshal response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,36 @@
+package openai
+
+// Usage is the number of tokens used by completion requests.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// prices are the prices of the completion models in USD per 1000 tokens.
+var prices = map[string]float64{
+	"gpt-3.5-turbo-instruct": 0.002,
+	"text-davinci-003":       0.02,
+	"text-davinci-002":       0.02,
+	"code-davinci-002":       0.02,
+	"text-curie-001":         0.002,
+	"text-babbage-001":       0.0005,
+	"text-ada-001":           0.0004,
+}
+
+// add adds the other usage to the usage.
+func (u *Usage) add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// Cost returns the estimated cost of the usage with the model in USD,
+// or false if the price of the model is not known.
+func (u Usage) Cost(model string) (float64, bool) {
+	price, ok := prices[model]
+	if !ok {
+		return 0, false
+	}
+	return float64(u.TotalTokens) / 1000 * price, true
+}
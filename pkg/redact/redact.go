@@ -0,0 +1,152 @@
+// Package redact removes secrets and personal data from prompts before they
+// are sent to a provider. Matches are replaced with placeholders, such as
+// REDACTED_EMAIL_1, that are mapped back to the original values in the response.
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// placeholderPrefix is the prefix of all placeholders.
+	placeholderPrefix = "REDACTED_"
+)
+
+var (
+	// ErrInvalidRule is returned when a user rule is not a valid regular expression.
+	ErrInvalidRule = errors.New("invalid redaction rule")
+
+	// ruleNameReplacer normalizes rule names used in placeholders.
+	ruleNameReplacer = strings.NewReplacer("-", "_", " ", "_", ".", "_")
+)
+
+// Rule replaces the matches of the pattern with placeholders.
+type Rule struct {
+	// Name is the name of the rule, it is a part of the placeholder.
+	Name string
+	// Pattern matches the values to redact. If the pattern has a capture group,
+	// only the first group is redacted, e.g. the token of "Bearer <token>".
+	Pattern *regexp.Regexp
+}
+
+// Detectors are the built-in rules, applied to every prompt.
+var Detectors = []Rule{
+	{Name: "aws_key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "aws_secret", Pattern: regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[=:]\s*["']?([A-Za-z0-9/+]{40})\b`)},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{Name: "token", Pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{8,})`)},
+	{Name: "email", Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{Name: "ip", Pattern: regexp.MustCompile(`\b(?:10\.\d{1,3}|172\.(?:1[6-9]|2\d|3[01])|192\.168)\.\d{1,3}\.\d{1,3}\b`)},
+}
+
+// ParseRules compiles user rules, a map of rule names to regular expressions.
+// The rules are sorted by name, so that they are applied in a stable order.
+func ParseRules(patterns map[string]string) ([]Rule, error) {
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make([]Rule, 0, len(patterns))
+	for _, name := range names {
+		pattern, err := regexp.Compile(patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidRule, name, err)
+		}
+		rules = append(rules, Rule{Name: name, Pattern: pattern})
+	}
+	return rules, nil
+}
+
+// Pipeline redacts prompts and restores the placeholders in responses.
+// The same value is always replaced with the same placeholder,
+// so that the provider can still relate the values in the prompt.
+type Pipeline struct {
+	mu    sync.Mutex
+	rules []Rule
+	// placeholders maps the redacted values to their placeholders.
+	placeholders map[string]string
+	// values maps the placeholders to the redacted values.
+	values map[string]string
+	// counts is the number of placeholders per rule.
+	counts map[string]int
+}
+
+// NewPipeline creates a pipeline that applies the rules in the provided order.
+func NewPipeline(rules ...Rule) *Pipeline {
+	return &Pipeline{
+		rules:        rules,
+		placeholders: make(map[string]string),
+		values:       make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+// Redact replaces the values matched by the rules with placeholders.
+func (p *Pipeline) Redact(text string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, rule := range p.rules {
+		text = rule.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			start, end := 0, len(match)
+			if sub := rule.Pattern.FindStringSubmatchIndex(match); len(sub) >= 4 && sub[2] >= 0 {
+				start, end = sub[2], sub[3]
+			}
+			value := match[start:end]
+			if _, ok := p.values[value]; ok {
+				// Already redacted by a previous rule.
+				return match
+			}
+			return match[:start] + p.placeholder(rule, value) + match[end:]
+		})
+	}
+	return text
+}
+
+// Restore replaces the placeholders with the original values.
+func (p *Pipeline) Restore(text string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.values) == 0 {
+		return text
+	}
+	// Longer placeholders first, so that REDACTED_IP_1 does not replace a part of REDACTED_IP_10.
+	placeholders := make([]string, 0, len(p.values))
+	for placeholder := range p.values {
+		placeholders = append(placeholders, placeholder)
+	}
+	sort.Slice(placeholders, func(i, j int) bool {
+		return len(placeholders[i]) > len(placeholders[j])
+	})
+
+	pairs := make([]string, 0, 2*len(placeholders))
+	for _, placeholder := range placeholders {
+		pairs = append(pairs, placeholder, p.values[placeholder])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// placeholder returns the placeholder of the value, creating a new one if needed.
+func (p *Pipeline) placeholder(rule Rule, value string) string {
+	if placeholder, ok := p.placeholders[value]; ok {
+		return placeholder
+	}
+	name := strings.ToUpper(ruleNameReplacer.Replace(rule.Name))
+	p.counts[name]++
+	placeholder := fmt.Sprintf("%s%s_%d", placeholderPrefix, name, p.counts[name])
+	p.placeholders[value] = placeholder
+	p.values[placeholder] = value
+
+	log.Debug().Str("rule", rule.Name).Str("placeholder", placeholder).Msg("redacted value")
+	return placeholder
+}
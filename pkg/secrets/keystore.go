@@ -0,0 +1,195 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// keystoreVersion is the version of the keystore file format.
+	keystoreVersion = 1
+
+	// scrypt parameters, recommended for interactive logins.
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	keyLength    = 32
+	saltLength   = 16
+	keystorePerm = 0600
+)
+
+var (
+	// ErrSecretNotFound is returned when the keystore does not contain the secret.
+	ErrSecretNotFound = errors.New("secret not found in keystore")
+	// ErrWrongPassphrase is returned when the keystore cannot be decrypted with the passphrase.
+	ErrWrongPassphrase = errors.New("wrong keystore passphrase")
+	// ErrEmptyPassphrase is returned when the passphrase is empty.
+	ErrEmptyPassphrase = errors.New("keystore passphrase is empty")
+)
+
+// keystoreFile is the on-disk format of the keystore.
+// Secrets are encrypted with AES-256-GCM, with a key derived from the passphrase with scrypt.
+type keystoreFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Keystore is a file with secrets encrypted with a passphrase.
+type Keystore struct {
+	// fs is the file system that holds the keystore file.
+	fs afero.Fs
+	// path is the path of the keystore file.
+	path string
+	// passphrase is used to derive the encryption key.
+	passphrase string
+	// secrets are the decrypted secrets by name.
+	secrets map[string]string
+}
+
+// OpenKeystore reads and decrypts the keystore file.
+// A missing file results in an empty keystore, that is created on Save.
+func OpenKeystore(fs afero.Fs, path, passphrase string) (*Keystore, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	keystore := &Keystore{
+		fs:         fs,
+		path:       os.ExpandEnv(path),
+		passphrase: passphrase,
+		secrets:    make(map[string]string),
+	}
+
+	data, err := afero.ReadFile(fs, keystore.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return keystore, nil
+		}
+		return nil, fmt.Errorf("failed to read keystore %s: %w", keystore.path, err)
+	}
+
+	var file keystoreFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keystore %s: %w", keystore.path, err)
+	}
+	if file.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", file.Version)
+	}
+
+	gcm, err := newCipher(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if err = json.Unmarshal(plaintext, &keystore.secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse keystore secrets: %w", err)
+	}
+	return keystore, nil
+}
+
+// Path returns the path of the keystore file.
+func (k *Keystore) Path() string {
+	return k.path
+}
+
+// Get returns the secret with the given name.
+func (k *Keystore) Get(name string) (string, error) {
+	secret, ok := k.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrSecretNotFound, name)
+	}
+	return secret, nil
+}
+
+// Set sets the secret with the given name.
+func (k *Keystore) Set(name, secret string) {
+	k.secrets[name] = secret
+}
+
+// Remove removes the secret with the given name.
+func (k *Keystore) Remove(name string) error {
+	if _, ok := k.secrets[name]; !ok {
+		return fmt.Errorf("%w: %q", ErrSecretNotFound, name)
+	}
+	delete(k.secrets, name)
+	return nil
+}
+
+// Names returns the sorted names of the secrets.
+func (k *Keystore) Names() []string {
+	names := make([]string, 0, len(k.secrets))
+	for name := range k.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save encrypts the secrets and writes the keystore file.
+// A new salt and nonce are generated on every save.
+func (k *Keystore) Save() error {
+	plaintext, err := json.Marshal(k.secrets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	file := keystoreFile{
+		Version: keystoreVersion,
+		Salt:    make([]byte, saltLength),
+	}
+	if _, err = rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := newCipher(k.passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal keystore: %w", err)
+	}
+	if err = k.fs.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(k.path), err)
+	}
+	if err = afero.WriteFile(k.fs, k.path, data, keystorePerm); err != nil {
+		return fmt.Errorf("failed to write keystore %s: %w", k.path, err)
+	}
+	return nil
+}
+
+// newCipher derives the key from the passphrase and creates an AES-GCM cipher.
+func newCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
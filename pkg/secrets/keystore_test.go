@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestKeystore_SaveAndOpen(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	keystore, err := OpenKeystore(fs, "/aai/keystore.json", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := keystore.Names(); len(names) != 0 {
+		t.Errorf("Names() of a missing keystore = %q, want none", names)
+	}
+	keystore.Set("openai", "sk-secret")
+	keystore.Set("github", "ghp-secret")
+	if err = keystore.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := afero.ReadFile(fs, "/aai/keystore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("keystore file contains a secret in plain text:\n%s", data)
+	}
+
+	opened, err := OpenKeystore(fs, "/aai/keystore.json", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := opened.Names(); !reflect.DeepEqual(names, []string{"github", "openai"}) {
+		t.Errorf("Names() = %q, want the sorted names of the saved secrets", names)
+	}
+	if secret, err := opened.Get("openai"); err != nil || secret != "sk-secret" {
+		t.Errorf("Get() = %q, %v, want the saved secret", secret, err)
+	}
+	if err = opened.Remove("openai"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = opened.Get("openai"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get() of a removed secret error = %v, want %v", err, ErrSecretNotFound)
+	}
+	if err = opened.Remove("openai"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Remove() of a removed secret error = %v, want %v", err, ErrSecretNotFound)
+	}
+}
+
+func TestOpenKeystore_Errors(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	keystore, err := OpenKeystore(fs, "keystore.json", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keystore.Set("openai", "sk-secret")
+	if err = keystore.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err = afero.WriteFile(fs, "v2.json", []byte(`{"version": 2}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = afero.WriteFile(fs, "invalid.json", []byte(`not json`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		passphrase string
+		err        error
+	}{
+		{name: "wrong passphrase", path: "keystore.json", passphrase: "wrong", err: ErrWrongPassphrase},
+		{name: "empty passphrase", path: "keystore.json", err: ErrEmptyPassphrase},
+		{name: "unsupported version", path: "v2.json", passphrase: "passphrase"},
+		{name: "invalid file", path: "invalid.json", passphrase: "passphrase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keystore, err := OpenKeystore(fs, tt.path, tt.passphrase)
+			if err == nil {
+				t.Fatalf("OpenKeystore() = %v, want an error", keystore)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("OpenKeystore() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+const (
+	// Masked replaces secrets in displayed values and logs.
+	Masked = "********"
+)
+
+// Mask returns the masked secret. References are not secrets themselves,
+// so they are returned unchanged, as well as empty values.
+func Mask(value string) string {
+	if value == "" || IsReference(value) {
+		return value
+	}
+	return Masked
+}
+
+// Redactor is a writer that masks registered secrets before writing to the underlying writer.
+// It is used as the output of loggers, so that secrets are never written to logs.
+type Redactor struct {
+	mu      sync.Mutex
+	w       io.Writer
+	secrets [][]byte
+}
+
+// NewRedactor creates a new Redactor that writes to w.
+func NewRedactor(w io.Writer) *Redactor {
+	return &Redactor{w: w}
+}
+
+// Add registers secrets that are masked from now on. Empty values are ignored.
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, []byte(secret))
+		}
+	}
+}
+
+// Write masks the secrets in p and writes it to the underlying writer.
+func (r *Redactor) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	redacted := p
+	for _, secret := range r.secrets {
+		redacted = bytes.ReplaceAll(redacted, secret, []byte(Masked))
+	}
+	if _, err := r.w.Write(redacted); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,39 @@
+package secrets
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	tests := map[string]string{
+		"":                "",
+		"sk-secret":       Masked,
+		"keystore:openai": "keystore:openai",
+		"env:OPENAI_KEY":  "env:OPENAI_KEY",
+	}
+	for value, want := range tests {
+		if got := Mask(value); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestRedactor_Write(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewRedactor(&out)
+	redactor.Add("sk-secret", "", "ghp-token")
+
+	line := []byte("key=sk-secret token=ghp-token key=sk-secret\n")
+	n, err := redactor.Write(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(line) {
+		t.Errorf("Write() = %d, want the length of the written bytes %d", n, len(line))
+	}
+	want := "key=" + Masked + " token=" + Masked + " key=" + Masked + "\n"
+	if out.String() != want {
+		t.Errorf("written = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,139 @@
+// Package secrets resolves secret references in config values,
+// so that secrets, such as API keys, are not stored in plain text.
+//
+// A reference is a config value with one of the schemes:
+//
+//	cmd:pass show openai      output of a shell command
+//	file:/run/secrets/openai  content of a file
+//	env:OPENAI_KEY            value of an environment variable
+//	keystore:openai           secret from the encrypted keystore
+//
+// Values without a scheme are plain text secrets and are returned unchanged.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// CmdScheme references the output of a shell command.
+	CmdScheme = "cmd:"
+	// FileScheme references the content of a file.
+	FileScheme = "file:"
+	// EnvScheme references the value of an environment variable.
+	EnvScheme = "env:"
+	// KeystoreScheme references a secret in the encrypted keystore.
+	KeystoreScheme = "keystore:"
+
+	// cmdTimeout is the maximum time of a secret command, it may ask for a password.
+	cmdTimeout = time.Minute
+)
+
+var (
+	// ErrEmptySecret is returned when a reference resolves to an empty secret.
+	ErrEmptySecret = errors.New("secret is empty")
+
+	// schemes is the list of supported reference schemes.
+	schemes = []string{CmdScheme, FileScheme, EnvScheme, KeystoreScheme}
+)
+
+// IsReference returns true if the value is a secret reference rather than a plain text secret.
+func IsReference(value string) bool {
+	for _, scheme := range schemes {
+		if strings.HasPrefix(value, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver resolves secret references.
+type Resolver struct {
+	// fs is the file system used by file references.
+	fs afero.Fs
+	// openKeystore opens the keystore, it is called only if a keystore reference is resolved.
+	openKeystore func() (*Keystore, error)
+}
+
+// NewResolver creates a new Resolver. The keystore is opened lazily,
+// since opening it may require a passphrase.
+func NewResolver(fs afero.Fs, openKeystore func() (*Keystore, error)) *Resolver {
+	return &Resolver{
+		fs:           fs,
+		openKeystore: openKeystore,
+	}
+}
+
+// Resolve returns the secret referenced by the value.
+// Plain text values are returned unchanged.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	var secret string
+	var err error
+
+	switch {
+	case strings.HasPrefix(value, CmdScheme):
+		secret, err = r.resolveCmd(ctx, strings.TrimPrefix(value, CmdScheme))
+	case strings.HasPrefix(value, FileScheme):
+		secret, err = r.resolveFile(strings.TrimPrefix(value, FileScheme))
+	case strings.HasPrefix(value, EnvScheme):
+		secret = os.Getenv(strings.TrimPrefix(value, EnvScheme))
+	case strings.HasPrefix(value, KeystoreScheme):
+		secret, err = r.resolveKeystore(strings.TrimPrefix(value, KeystoreScheme))
+	default:
+		return value, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %q: %w", value, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("failed to resolve secret %q: %w", value, ErrEmptySecret)
+	}
+	return secret, nil
+}
+
+// resolveCmd runs the command with sh and returns its output.
+// The command can interact with the user, e.g. to ask for a gpg passphrase.
+func (r *Resolver) resolveCmd(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, cmdTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run secret command: %w", err)
+	}
+	return firstLine(string(out)), nil
+}
+
+func (r *Resolver) resolveFile(path string) (string, error) {
+	data, err := afero.ReadFile(r.fs, os.ExpandEnv(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return firstLine(string(data)), nil
+}
+
+func (r *Resolver) resolveKeystore(name string) (string, error) {
+	keystore, err := r.openKeystore()
+	if err != nil {
+		return "", err
+	}
+	return keystore.Get(name)
+}
+
+// firstLine returns the first line of the text, without surrounding whitespace.
+// Tools such as pass print the secret in the first line and metadata in the following lines.
+func firstLine(text string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
+}
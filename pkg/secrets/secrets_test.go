@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	t.Setenv("AAI_TEST_SECRET", "sk-env")
+	t.Setenv("AAI_TEST_EMPTY", "")
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "/run/secrets/openai", []byte("sk-file\nuser: test\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keystore, err := OpenKeystore(fs, "/keystore.json", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keystore.Set("openai", "sk-keystore")
+	errLocked := errors.New("keystore is locked")
+
+	tests := []struct {
+		name     string
+		value    string
+		keystore func() (*Keystore, error)
+		want     string
+		err      error
+	}{
+		{name: "plain text", value: "sk-plain", want: "sk-plain"},
+		{name: "cmd", value: "cmd:printf 'sk-cmd\\nmetadata\\n'", want: "sk-cmd"},
+		{name: "cmd fails", value: "cmd:exit 1"},
+		{name: "cmd empty output", value: "cmd:true", err: ErrEmptySecret},
+		{name: "file", value: "file:/run/secrets/openai", want: "sk-file"},
+		{name: "missing file", value: "file:/run/secrets/missing"},
+		{name: "env", value: "env:AAI_TEST_SECRET", want: "sk-env"},
+		{name: "empty env", value: "env:AAI_TEST_EMPTY", err: ErrEmptySecret},
+		{name: "keystore", value: "keystore:openai", want: "sk-keystore"},
+		{name: "missing keystore secret", value: "keystore:github", err: ErrSecretNotFound},
+		{name: "keystore cannot be opened", value: "keystore:openai", keystore: func() (*Keystore, error) { return nil, errLocked }, err: errLocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openKeystore := tt.keystore
+			if openKeystore == nil {
+				openKeystore = func() (*Keystore, error) { return keystore, nil }
+			}
+			got, err := NewResolver(fs, openKeystore).Resolve(context.Background(), tt.value)
+			if tt.want != "" {
+				if err != nil || got != tt.want {
+					t.Errorf("Resolve() = %q, %v, want %q", got, err, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Resolve() = %q, want an error", got)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Resolve() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestResolver_KeystoreOpenedLazily(t *testing.T) {
+	resolver := NewResolver(afero.NewMemMapFs(), func() (*Keystore, error) {
+		t.Error("the keystore is opened, but no keystore reference is resolved")
+		return nil, nil
+	})
+	if _, err := resolver.Resolve(context.Background(), "sk-plain"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,69 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Dialect is a shell language.
+type Dialect string
+
+const (
+	// Bash is the GNU Bash shell.
+	Bash Dialect = "bash"
+	// Posix is the POSIX shell language.
+	Posix Dialect = "posix-sh"
+	// Zsh is the Z shell.
+	Zsh Dialect = "zsh"
+	// Fish is the friendly interactive shell.
+	Fish Dialect = "fish"
+	// PowerShell is the Microsoft PowerShell.
+	PowerShell Dialect = "powershell"
+)
+
+var (
+	// ErrUnknownDialect is returned when a dialect is not supported.
+	ErrUnknownDialect = errors.New("unknown shell dialect")
+
+	// Dialects is the list of supported dialects.
+	Dialects = []Dialect{Bash, Posix, Zsh, Fish, PowerShell}
+
+	// variants are the parser variants of the dialects that can be validated.
+	variants = map[Dialect]syntax.LangVariant{
+		Bash:  syntax.LangBash,
+		Posix: syntax.LangPOSIX,
+	}
+)
+
+// ParseDialect returns the dialect with the given name.
+func ParseDialect(name string) (Dialect, error) {
+	for _, dialect := range Dialects {
+		if string(dialect) == name {
+			return dialect, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownDialect, name)
+}
+
+// CanValidate returns true if scripts in the dialect can be validated with Validate.
+func (d Dialect) CanValidate() bool {
+	_, ok := variants[d]
+	return ok
+}
+
+// Validate parses the script and returns an error if it is not a valid script
+// in the dialect. Scripts in dialects that cannot be validated are always valid.
+func Validate(script string, dialect Dialect) error {
+	variant, ok := variants[dialect]
+	if !ok {
+		return nil
+	}
+	_, err := syntax.NewParser(syntax.Variant(variant)).Parse(strings.NewReader(script), "")
+	if err != nil {
+		return fmt.Errorf("invalid %s script: %w", dialect, err)
+	}
+	return nil
+}
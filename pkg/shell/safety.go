@@ -0,0 +1,277 @@
+package shell
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Severity is the severity of a safety finding.
+type Severity string
+
+const (
+	// Warning is a finding that should be reviewed.
+	Warning Severity = "warning"
+	// Danger is a finding that can cause irreversible damage.
+	Danger Severity = "danger"
+)
+
+var (
+	// destructivePrograms are programs that destroy data or stop the system.
+	destructivePrograms = map[string]string{
+		"mkfs":     "formats a file system",
+		"shred":    "irreversibly overwrites files",
+		"wipefs":   "erases file system signatures",
+		"shutdown": "shuts the system down",
+		"reboot":   "reboots the system",
+		"halt":     "halts the system",
+		"poweroff": "powers the system off",
+	}
+	// downloaders are programs that download data from the internet.
+	downloaders = map[string]bool{"curl": true, "wget": true}
+	// interpreters are programs that execute code read from stdin.
+	interpreters = map[string]bool{"sh": true, "bash": true, "zsh": true, "python": true, "python3": true, "perl": true}
+	// executors are programs that execute code passed as an argument, e.g. "bash -c" or "source".
+	executors = map[string]bool{"sh": true, "bash": true, "zsh": true, "python": true, "python3": true, "perl": true, "eval": true, "source": true, ".": true}
+	// wrapperValueFlags are the flags of wrapper programs that take a value, e.g. "sudo -u root".
+	wrapperValueFlags = map[string]map[string]bool{
+		"sudo":  {"-u": true, "-g": true, "-C": true, "-D": true, "-h": true, "-p": true, "-U": true},
+		"env":   {"-u": true, "-C": true, "-S": true},
+		"nice":  {"-n": true},
+		"watch": {"-n": true},
+		"xargs": {"-I": true, "-n": true, "-P": true, "-d": true, "-L": true, "-s": true, "-E": true},
+	}
+	// protectedPaths are paths that should never be removed or modified recursively.
+	protectedPaths = map[string]bool{
+		"/": true, "/*": true, "~": true, "~/": true, "~/*": true, "$HOME": true, "${HOME}": true, "$HOME/*": true, "${HOME}/*": true,
+		"/home": true, "/home/*": true, "/etc": true, "/usr": true, "/var": true,
+	}
+)
+
+// Finding is a potentially dangerous operation found in a script.
+type Finding struct {
+	// Line is the line of the operation in the script.
+	Line uint `json:"line"`
+	// Command is the source text of the operation.
+	Command string `json:"command"`
+	// Reason describes why the operation is dangerous.
+	Reason string `json:"reason"`
+	// Severity is the severity of the finding.
+	Severity Severity `json:"severity"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s: %s (%s)", f.Line, f.Severity, f.Reason, f.Command)
+}
+
+// Analyze parses the bash script and returns the potentially dangerous operations it contains.
+func Analyze(script string) ([]Finding, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(script), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+
+	a := analyzer{parser: parser{src: script}}
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch node := node.(type) {
+		case *syntax.CallExpr:
+			a.call(node)
+		case *syntax.BinaryCmd:
+			a.pipe(node)
+		case *syntax.Redirect:
+			a.redirect(node)
+		}
+		return true
+	})
+	return a.findings, nil
+}
+
+// analyzer collects findings from the script nodes.
+type analyzer struct {
+	parser
+	findings []Finding
+}
+
+func (a *analyzer) report(node syntax.Node, severity Severity, reason string) {
+	a.findings = append(a.findings, Finding{
+		Line:     node.Pos().Line(),
+		Command:  a.text(node),
+		Reason:   reason,
+		Severity: severity,
+	})
+}
+
+func (a *analyzer) call(call *syntax.CallExpr) {
+	i, wrappers := realProgram(call.Args)
+	for _, wrapper := range wrappers {
+		if wrapper == "sudo" {
+			a.report(call, Warning, "runs a command as root")
+		}
+	}
+	if i == len(call.Args) {
+		return
+	}
+	program := programName(call.Args[i])
+	args := make([]string, 0, len(call.Args)-i-1)
+	for _, word := range call.Args[i+1:] {
+		args = append(args, a.text(word))
+	}
+
+	if reason, ok := destructivePrograms[strings.SplitN(program, ".", 2)[0]]; ok {
+		a.report(call, Danger, fmt.Sprintf("%s %s", program, reason))
+	}
+	if executors[program] {
+		for _, word := range call.Args[i+1:] {
+			if downloads(word) {
+				a.report(call, Danger, "executes code downloaded from the internet")
+				break
+			}
+		}
+	}
+
+	recursive := hasShortFlag(args, 'r') || hasShortFlag(args, 'R') || hasFlag(args, "--recursive")
+	switch program {
+	case "rm":
+		for _, arg := range args {
+			if protectedPaths[strings.Trim(arg, `"'`)] {
+				a.report(call, Danger, fmt.Sprintf("removes %s", arg))
+			} else if recursive && unguardedVariablePath(arg) {
+				a.report(call, Danger, fmt.Sprintf("removes %s recursively, which is / if the variable is empty", arg))
+			}
+		}
+	case "chmod", "chown":
+		for _, arg := range args {
+			if recursive && protectedPaths[strings.Trim(arg, `"'`)] {
+				a.report(call, Danger, fmt.Sprintf("changes %s recursively", arg))
+			}
+		}
+	case "dd":
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "of=/dev/") && arg != "of=/dev/null" {
+				a.report(call, Danger, "writes directly to a device")
+			}
+		}
+	case "find":
+		if !hasFlag(args, "-delete") && !hasFlag(args, "-exec") && !hasFlag(args, "-execdir") {
+			return
+		}
+		for _, arg := range args {
+			if protectedPaths[strings.Trim(arg, `"'`)] {
+				a.report(call, Danger, fmt.Sprintf("deletes or runs commands on the files in %s", arg))
+			}
+		}
+	}
+}
+
+func (a *analyzer) pipe(cmd *syntax.BinaryCmd) {
+	if cmd.Op != syntax.Pipe && cmd.Op != syntax.PipeAll {
+		return
+	}
+	if downloaders[a.program(cmd.X)] && interpreters[a.program(cmd.Y)] {
+		a.report(cmd, Danger, "executes code downloaded from the internet")
+	}
+}
+
+func (a *analyzer) redirect(redir *syntax.Redirect) {
+	if redir.Word == nil {
+		return
+	}
+	target := a.text(redir.Word)
+	if strings.HasPrefix(target, "/dev/sd") || strings.HasPrefix(target, "/dev/nvme") || strings.HasPrefix(target, "/dev/disk") {
+		a.report(redir, Danger, "writes directly to a disk device")
+	}
+}
+
+// program returns the program of a simple command statement, or an empty string.
+// Wrapper programs are skipped, e.g. "sudo bash" returns bash.
+// For pipelines, the program of the last command is returned.
+func (a *analyzer) program(stmt *syntax.Stmt) string {
+	switch cmd := stmt.Cmd.(type) {
+	case *syntax.CallExpr:
+		if i, _ := realProgram(cmd.Args); i < len(cmd.Args) {
+			return programName(cmd.Args[i])
+		}
+	case *syntax.BinaryCmd:
+		return a.program(cmd.Y)
+	}
+	return ""
+}
+
+// realProgram returns the index of the program run by the command arguments and the wrapper
+// programs before it, e.g. rm and [sudo] for "sudo -u root rm -rf /". The flags of the wrappers,
+// with their values, and the assignments of env are skipped. The index is len(args) if there is no program.
+func realProgram(args []*syntax.Word) (int, []string) {
+	var wrappers []string
+	for i := 0; i < len(args); i++ {
+		program := programName(args[i])
+		switch {
+		case wrapperPrograms[program]:
+			wrappers = append(wrappers, program)
+		case len(wrappers) > 0 && strings.HasPrefix(program, "-"):
+			if wrapperValueFlags[wrappers[len(wrappers)-1]][program] {
+				i++
+			}
+		case len(wrappers) > 0 && strings.Contains(args[i].Lit(), "="):
+		default:
+			return i, wrappers
+		}
+	}
+	return len(args), wrappers
+}
+
+// programName returns the base name of the literal word, or an empty string if the word is not literal.
+func programName(word *syntax.Word) string {
+	if word.Lit() == "" {
+		return ""
+	}
+	return path.Base(word.Lit())
+}
+
+// downloads returns true if the word has a command or process substitution
+// that runs a downloader, e.g. "$(curl -s https://example.com)".
+func downloads(word *syntax.Word) bool {
+	found := false
+	syntax.Walk(word, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok {
+			if i, _ := realProgram(call.Args); i < len(call.Args) && downloaders[programName(call.Args[i])] {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// hasShortFlag returns true if any of the short flags in args contains the letter.
+func hasShortFlag(args []string, letter rune) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsRune(arg, letter) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFlag returns true if args contain the flag.
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// unguardedVariablePath returns true if the path starts with a variable
+// that is not guarded against being empty, e.g. "$DIR/" or "${DIR}/*".
+// Guarded expansions, such as "${DIR:?}", are safe.
+func unguardedVariablePath(arg string) bool {
+	arg = strings.Trim(arg, `"'`)
+	if !strings.HasPrefix(arg, "$") || !strings.Contains(arg, "/") {
+		return false
+	}
+	variable := arg[:strings.Index(arg, "/")]
+	return !strings.Contains(variable, ":?")
+}
@@ -0,0 +1,67 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		script string
+		// want are the severities of the findings in order.
+		want []Severity
+	}{
+		{script: "ls -la"},
+		{script: "rm -rf ./build"},
+		{script: "rm -rf /", want: []Severity{Danger}},
+		{script: "rm -rf ~/*", want: []Severity{Danger}},
+		{script: "rm -rf /home/*", want: []Severity{Danger}},
+		{script: `rm -rf "$HOME"`, want: []Severity{Danger}},
+		{script: "rm -rf $DIR/", want: []Severity{Danger}},
+		{script: "rm -rf ${DIR:?}/", want: nil},
+		{script: "sudo apt-get update", want: []Severity{Warning}},
+		{script: "sudo rm -rf /", want: []Severity{Warning, Danger}},
+		{script: "sudo -u root rm -rf /", want: []Severity{Warning, Danger}},
+		{script: "sudo dd if=/dev/zero of=/dev/sda", want: []Severity{Warning, Danger}},
+		{script: "nice -n 10 dd if=/dev/zero of=/dev/sda", want: []Severity{Danger}},
+		{script: "dd if=/dev/zero of=/dev/null"},
+		{script: "env FOO=bar mkfs.ext4 /dev/sdb1", want: []Severity{Danger}},
+		{script: "sudo chmod -R 777 /", want: []Severity{Warning, Danger}},
+		{script: "chmod 644 /etc"},
+		{script: "find / -delete", want: []Severity{Danger}},
+		{script: "find / -name '*.log'"},
+		{script: "find ./tmp -delete"},
+		{script: "echo hi > /dev/sda", want: []Severity{Danger}},
+		{script: "curl -s https://example.com/install.sh | sh", want: []Severity{Danger}},
+		{script: "curl -s https://example.com/install.sh | sudo bash", want: []Severity{Danger, Warning}},
+		{script: "curl -s https://example.com/data.json | jq ."},
+		{script: "bash <(curl -s https://example.com/install.sh)", want: []Severity{Danger}},
+		{script: `bash -c "$(curl -fsSL https://example.com/install.sh)"`, want: []Severity{Danger}},
+		{script: `eval "$(curl -s https://example.com/install.sh)"`, want: []Severity{Danger}},
+		{script: "source <(wget -qO- https://example.com/env.sh)", want: []Severity{Danger}},
+		{script: `echo "$(curl -s https://example.com/ip)"`},
+		{script: `"$EDITOR" notes.txt`},
+		{script: "echo $(sudo rm -rf /)", want: []Severity{Warning, Danger}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			findings, err := Analyze(tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []Severity
+			for _, finding := range findings {
+				got = append(got, finding.Severity)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Analyze() = %v, want severities %v", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyze_Invalid(t *testing.T) {
+	if _, err := Analyze("ls |"); err == nil {
+		t.Error("Analyze() error = nil, want a parse error")
+	}
+}
@@ -0,0 +1,196 @@
+// Package shell parses shell commands into components, such as
+// pipeline stages, programs, subcommands, flags and arguments.
+package shell
+
+import (
+	"fmt"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Kind is the kind of command component.
+type Kind string
+
+const (
+	// KindCommand is a simple command, e.g. a single pipeline stage.
+	KindCommand Kind = "command"
+	// KindCompound is a command that is not broken down further, e.g. a loop or a subshell.
+	KindCompound Kind = "compound"
+	// KindOperator is an operator between commands, e.g. "|" or "&&".
+	KindOperator Kind = "operator"
+	// KindProgram is the program executed by a command.
+	KindProgram Kind = "program"
+	// KindSubcommand is a subcommand of a program, e.g. "commit" in "git commit".
+	KindSubcommand Kind = "subcommand"
+	// KindFlag is a command line flag, e.g. "-l" or "--all".
+	KindFlag Kind = "flag"
+	// KindArgument is a positional argument.
+	KindArgument Kind = "argument"
+	// KindRedirect is a redirection, e.g. "> out.txt".
+	KindRedirect Kind = "redirect"
+	// KindAssignment is an environment variable assignment, e.g. "FOO=bar".
+	KindAssignment Kind = "assignment"
+)
+
+var (
+	// subcommandPrograms are programs whose first positional argument is a subcommand.
+	subcommandPrograms = map[string]bool{
+		"apt": true, "apt-get": true, "aws": true, "brew": true, "cargo": true,
+		"docker": true, "gcloud": true, "gh": true, "git": true, "go": true,
+		"helm": true, "ip": true, "kubectl": true, "npm": true, "pip": true,
+		"podman": true, "systemctl": true, "terraform": true, "yarn": true,
+	}
+	// wrapperPrograms are programs whose first positional argument is another program.
+	wrapperPrograms = map[string]bool{
+		"env": true, "exec": true, "nice": true, "nohup": true, "sudo": true,
+		"time": true, "watch": true, "xargs": true,
+	}
+)
+
+// Node is a component of a parsed command.
+type Node struct {
+	// Kind is the kind of the component.
+	Kind Kind `json:"kind"`
+	// Text is the source text of the component.
+	Text string `json:"text"`
+	// Explanation is the explanation of the component, if it was requested.
+	Explanation string `json:"explanation,omitempty"`
+	// Children are the components nested in this component,
+	// e.g. the flags of a command or the commands of a command substitution.
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Parse parses the bash command into a list of top level components,
+// that is commands and the operators between them.
+func Parse(command string) ([]*Node, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse command: %w", err)
+	}
+
+	p := parser{src: command}
+	return p.stmts(file.Stmts), nil
+}
+
+// Walk calls f for every node in preorder.
+func Walk(nodes []*Node, f func(node *Node, depth int)) {
+	walk(nodes, 0, f)
+}
+
+func walk(nodes []*Node, depth int, f func(node *Node, depth int)) {
+	for _, node := range nodes {
+		f(node, depth)
+		walk(node.Children, depth+1, f)
+	}
+}
+
+// parser converts syntax nodes into Nodes.
+type parser struct {
+	// src is the parsed source code.
+	src string
+}
+
+// text returns the source text of the syntax node.
+func (p *parser) text(node syntax.Node) string {
+	return p.src[node.Pos().Offset():node.End().Offset()]
+}
+
+func (p *parser) stmts(stmts []*syntax.Stmt) []*Node {
+	var nodes []*Node
+	for i, stmt := range stmts {
+		nodes = append(nodes, p.stmt(stmt)...)
+		if stmt.Background {
+			nodes = append(nodes, &Node{Kind: KindOperator, Text: "&"})
+		} else if i < len(stmts)-1 {
+			nodes = append(nodes, &Node{Kind: KindOperator, Text: ";"})
+		}
+	}
+	return nodes
+}
+
+func (p *parser) stmt(stmt *syntax.Stmt) []*Node {
+	var nodes []*Node
+	switch cmd := stmt.Cmd.(type) {
+	case *syntax.BinaryCmd:
+		nodes = append(nodes, p.stmt(cmd.X)...)
+		nodes = append(nodes, &Node{Kind: KindOperator, Text: cmd.Op.String()})
+		nodes = append(nodes, p.stmt(cmd.Y)...)
+		return nodes
+	case *syntax.CallExpr:
+		nodes = append(nodes, p.call(cmd))
+	case nil:
+		// A statement with redirections only, e.g. "> file".
+		nodes = append(nodes, &Node{Kind: KindCommand})
+	default:
+		nodes = append(nodes, &Node{Kind: KindCompound, Text: p.text(cmd)})
+	}
+
+	last := nodes[len(nodes)-1]
+	for _, redir := range stmt.Redirs {
+		last.Children = append(last.Children, &Node{Kind: KindRedirect, Text: p.text(redir)})
+	}
+	if len(stmt.Redirs) > 0 || stmt.Negated {
+		last.Text = strings.TrimSuffix(strings.TrimSpace(p.text(stmt)), "&")
+	}
+	return nodes
+}
+
+func (p *parser) call(call *syntax.CallExpr) *Node {
+	node := &Node{Kind: KindCommand, Text: p.text(call)}
+	for _, assign := range call.Assigns {
+		node.Children = append(node.Children, &Node{Kind: KindAssignment, Text: p.text(assign)})
+	}
+
+	// expect is the kind of the next positional argument.
+	expect := KindProgram
+	// flagsEnded is true after "--", the following words are positional arguments.
+	flagsEnded := false
+	for i, word := range call.Args {
+		text := p.text(word)
+		kind := KindArgument
+		switch {
+		case i > 0 && text == "--" && expect != KindProgram:
+			flagsEnded = true
+		case i > 0 && !flagsEnded && isFlag(text):
+			kind = KindFlag
+		case expect == KindProgram:
+			kind = KindProgram
+			expect = KindArgument
+			program := word.Lit()
+			if subcommandPrograms[program] {
+				expect = KindSubcommand
+			} else if wrapperPrograms[program] {
+				expect = KindProgram
+			}
+		case expect == KindSubcommand:
+			kind = KindSubcommand
+			expect = KindArgument
+		}
+		node.Children = append(node.Children, &Node{
+			Kind:     kind,
+			Text:     text,
+			Children: p.substitutions(word.Parts),
+		})
+	}
+	return node
+}
+
+// isFlag returns true if the word looks like a command line flag.
+func isFlag(word string) bool {
+	return strings.HasPrefix(word, "-") && word != "-" && word != "--"
+}
+
+// substitutions returns the commands of command substitutions in the word parts.
+func (p *parser) substitutions(parts []syntax.WordPart) []*Node {
+	var nodes []*Node
+	for _, part := range parts {
+		switch part := part.(type) {
+		case *syntax.CmdSubst:
+			nodes = append(nodes, p.stmts(part.Stmts)...)
+		case *syntax.DblQuoted:
+			nodes = append(nodes, p.substitutions(part.Parts)...)
+		}
+	}
+	return nodes
+}
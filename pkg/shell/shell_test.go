@@ -0,0 +1,123 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// format returns the nodes as "kind:text" items, with the children in brackets.
+func format(nodes []*Node) string {
+	items := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		item := fmt.Sprintf("%s:%s", node.Kind, node.Text)
+		if len(node.Children) > 0 {
+			item += " [" + format(node.Children) + "]"
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ", ")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{
+			name:    "flags and arguments",
+			command: "ls -la /tmp",
+			want:    "command:ls -la /tmp [program:ls, flag:-la, argument:/tmp]",
+		},
+		{
+			name:    "pipe",
+			command: "ps aux | grep go",
+			want:    "command:ps aux [program:ps, argument:aux], operator:|, command:grep go [program:grep, argument:go]",
+		},
+		{
+			name:    "and or",
+			command: "make && echo ok || echo failed",
+			want:    "command:make [program:make], operator:&&, command:echo ok [program:echo, argument:ok], operator:||, command:echo failed [program:echo, argument:failed]",
+		},
+		{
+			name:    "list and background",
+			command: "cd /tmp; sleep 10 &",
+			want:    "command:cd /tmp [program:cd, argument:/tmp], operator:;, command:sleep 10 [program:sleep, argument:10], operator:&",
+		},
+		{
+			name:    "subcommand",
+			command: "git commit -m 'fix bug'",
+			want:    "command:git commit -m 'fix bug' [program:git, subcommand:commit, flag:-m, argument:'fix bug']",
+		},
+		{
+			name:    "wrapper",
+			command: "sudo -E apt-get install vim",
+			want:    "command:sudo -E apt-get install vim [program:sudo, flag:-E, program:apt-get, subcommand:install, argument:vim]",
+		},
+		{
+			name:    "redirects",
+			command: "sort data.txt > sorted.txt 2>&1",
+			want:    "command:sort data.txt > sorted.txt 2>&1 [program:sort, argument:data.txt, redirect:> sorted.txt, redirect:2>&1]",
+		},
+		{
+			name:    "assignment",
+			command: "LANG=C date",
+			want:    "command:LANG=C date [assignment:LANG=C, program:date]",
+		},
+		{
+			name:    "command substitution",
+			command: `kill "$(pgrep -f server)"`,
+			want:    `command:kill "$(pgrep -f server)" [program:kill, argument:"$(pgrep -f server)" [command:pgrep -f server [program:pgrep, flag:-f, argument:server]]]`,
+		},
+		{
+			name:    "compound",
+			command: "for f in *.txt; do echo $f; done",
+			want:    "compound:for f in *.txt; do echo $f; done",
+		},
+		{
+			name:    "negated",
+			command: "! grep -q x file",
+			want:    "command:! grep -q x file [program:grep, flag:-q, argument:x, argument:file]",
+		},
+		{
+			name:    "double dash",
+			command: "rm -- -file",
+			want:    "command:rm -- -file [program:rm, argument:--, argument:-file]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := Parse(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := format(nodes); got != tt.want {
+				t.Errorf("Parse() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, command := range []string{"ls |", "echo $(", "if true; then"} {
+		if _, err := Parse(command); err == nil {
+			t.Errorf("Parse(%q) error = nil, want an error", command)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	nodes, err := Parse(`echo "$(date)" | wc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	Walk(nodes, func(node *Node, depth int) {
+		got = append(got, fmt.Sprintf("%d:%s", depth, node.Text))
+	})
+	want := []string{`0:echo "$(date)"`, "1:echo", `1:"$(date)"`, "2:date", "3:date", "0:|", "0:wc", "1:wc"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Walk() visited %q, want %q", got, want)
+	}
+}